@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const accountCtx accountKey = "account"
+
+type accountKey string
+
+var errOwnAccount = errors.New("admins cannot manage their own account")
+
+// ListUsers godoc
+//
+//	@Summary		Lists the users
+//	@Description	Lists active and inactive users, newest first, for the admins
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			search	query		string	false	"Matches usernames and emails"
+//	@Param			role	query		string	false	"Role name"
+//	@Param			active	query		bool	false	"Only active or inactive users"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200		{object}	[]store.User
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users [get]
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	uq := store.PaginatedUserQuery{
+		Limit: 20,
+	}
+
+	uq, err := uq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(uq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	users, err := app.dbStore.Users.Search(r.Context(), uq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, err := store.NextUserCursor(users, uq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, users, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+// UpdateUserRole godoc
+//
+//	@Summary		Changes the role of a user
+//	@Description	Gives a role to a user, admins cannot give a role above their own
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int					true	"User ID"
+//	@Param			payload	body		UpdateRolePayload	true	"Role"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/role [put]
+func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	role, err := app.dbStore.Roles.GetByName(ctx, payload.Role)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if role.Level > getUserFromContext(r).Role.Level {
+		app.forbiddenError(w, r)
+		return
+	}
+
+	account := getAccountFromContext(r)
+	if err := app.dbStore.Users.SetRole(ctx, account.ID, role.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	updated := *account
+	updated.RoleID = role.ID
+	updated.Role = *role
+
+	app.accountChanged(r, store.AuditUserRoleChanged, account, &updated)
+
+	if err := app.jsonResponse(w, http.StatusOK, updated); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ActivateAccount godoc
+//
+//	@Summary		Reactivates a user
+//	@Description	Lifts the deactivation of a user, a user who never confirmed its email still has to
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	store.User
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/activate [put]
+func (app *application) activateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+
+	if err := app.dbStore.Users.Reactivate(r.Context(), account.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	updated := *account
+	updated.DeactivatedAt = ""
+	updated.DeactivatedBy = nil
+
+	app.accountChanged(r, store.AuditUserActivated, account, &updated)
+
+	if err := app.jsonResponse(w, http.StatusOK, updated); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeactivateAccount godoc
+//
+//	@Summary		Deactivates a user
+//	@Description	Deactivates a user and signs it out of all its sessions, it cannot sign in or
+//	@Description	activate its account again until an admin reactivates it
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	store.User
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/deactivate [put]
+func (app *application) deactivateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+	admin := getUserFromContext(r)
+
+	if err := app.dbStore.Users.Deactivate(r.Context(), account.ID, admin.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	updated := *account
+	updated.DeactivatedAt = time.Now().UTC().Format(time.RFC3339)
+	updated.DeactivatedBy = &admin.ID
+
+	app.accountChanged(r, store.AuditUserDeactivated, account, &updated)
+
+	if err := app.jsonResponse(w, http.StatusOK, updated); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteAccount godoc
+//
+//	@Summary		Deletes a user
+//	@Description	Deletes a user for good with its posts and comments
+//	@Tags			admin
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User deleted"
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID} [delete]
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromContext(r)
+
+	if err := app.dbStore.Users.Delete(r.Context(), account.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.accountChanged(r, store.AuditUserDeleted, account, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// accountChanged records the change in the audit trail and evicts the cached user so that the
+// change applies to its next request.
+func (app *application) accountChanged(
+	r *http.Request, action string, before *store.User, after *store.User,
+) {
+	app.recordAudit(r, action, store.AuditTargetUser, before.ID, before, after)
+
+	if !app.config.cache.enabled {
+		return
+	}
+
+	if err := app.cacheStore.Users.Delete(r.Context(), before.ID); err != nil {
+		app.logger.Error("failed to evict user from cache", "userID", before.ID, "error", err)
+	}
+}
+
+// accountContextMiddleware loads the user managed by an admin, active or not. Admins cannot
+// manage their own account or the accounts of users with a higher role.
+func (app *application) accountContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		admin := getUserFromContext(r)
+		if admin.ID == userID {
+			app.badRequestError(w, r, errOwnAccount)
+			return
+		}
+
+		ctx := r.Context()
+
+		account, err := app.dbStore.Users.GetAccount(ctx, userID)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if account.Role.Level > admin.Role.Level {
+			app.forbiddenError(w, r)
+			return
+		}
+
+		ctx = context.WithValue(ctx, accountCtx, account)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAccountFromContext(r *http.Request) *store.User {
+	account, _ := r.Context().Value(accountCtx).(*store.User)
+	return account
+}
+
+// RestorePost godoc
+//
+//	@Summary		Restores a deleted post
+//	@Description	Restores a post deleted by its author or a moderator before it is purged
+//	@Tags			admin
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Success		200		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error	"Post not found or not deleted"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/posts/{postID}/restore [put]
+func (app *application) restorePostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.dbStore.Posts.Restore(ctx, postID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// The post can still be hidden by a moderator, it is then not found
+	post, err := app.dbStore.Posts.GetByID(ctx, postID)
+	if err != nil && err != store.ErrNotFound {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordAudit(r, store.AuditPostRestored, store.AuditTargetPost, postID, nil, post)
+	app.trendingChanged(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RestoreComment godoc
+//
+//	@Summary		Restores a deleted comment
+//	@Description	Restores a comment deleted by its author or a moderator before it is purged
+//	@Tags			admin
+//	@Produce		json
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Comment not found or not deleted"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/comments/{commentID}/restore [put]
+func (app *application) restoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.dbStore.Comments.Restore(ctx, commentID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// The comment can still be hidden by a moderator, it is then not found
+	comment, err := app.dbStore.Comments.GetByID(ctx, commentID)
+	if err != nil && err != store.ErrNotFound {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordAudit(
+		r, store.AuditCommentRestored, store.AuditTargetComment, commentID, nil, comment,
+	)
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
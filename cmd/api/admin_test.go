@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// adminUserStore signs the test token user in with the given role
+type adminUserStore struct {
+	store.MockUserStore
+	role store.Role
+}
+
+func (s *adminUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	return &store.User{ID: userID, RoleID: s.role.ID, Role: s.role}, nil
+}
+
+func TestAdminUsers(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path string, body io.Reader) int {
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux).Code
+	}
+
+	t.Run("should forbid users below admin", func(t *testing.T) {
+		app.dbStore.Users = &adminUserStore{role: store.Role{ID: 2, Name: "moderator", Level: 2}}
+
+		checkResponseCode(t, http.StatusForbidden, request(http.MethodGet, "/v1/admin/users", nil))
+		checkResponseCode(
+			t, http.StatusForbidden, request(http.MethodDelete, "/v1/admin/users/2", nil),
+		)
+	})
+
+	app.dbStore.Users = &adminUserStore{role: store.Role{ID: 3, Name: "admin", Level: 3}}
+	audit := app.dbStore.Audit.(*store.MockAuditStore)
+
+	t.Run("should list users", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/admin/users", nil))
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodGet, "/v1/admin/users?active=false&role=user", nil),
+		)
+		checkResponseCode(
+			t, http.StatusBadRequest, request(http.MethodGet, "/v1/admin/users?active=maybe", nil),
+		)
+		checkResponseCode(
+			t, http.StatusBadRequest, request(http.MethodGet, "/v1/admin/users?limit=100", nil),
+		)
+	})
+
+	t.Run("should not manage their own account", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusBadRequest, request(http.MethodPut, "/v1/admin/users/1/deactivate", nil),
+		)
+		if len(audit.Events) != 0 {
+			t.Errorf("expected no audit events but got %d", len(audit.Events))
+		}
+	})
+
+	t.Run("should change roles", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, request(
+			http.MethodPut, "/v1/admin/users/2/role", strings.NewReader(`{"role": "owner"}`),
+		))
+		checkResponseCode(t, http.StatusOK, request(
+			http.MethodPut, "/v1/admin/users/2/role", strings.NewReader(`{"role": "moderator"}`),
+		))
+	})
+
+	t.Run("should record every action", func(t *testing.T) {
+		audit.Events = nil
+
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodPut, "/v1/admin/users/2/deactivate", nil),
+		)
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodPut, "/v1/admin/users/2/activate", nil),
+		)
+		checkResponseCode(
+			t, http.StatusNoContent, request(http.MethodDelete, "/v1/admin/users/2", nil),
+		)
+
+		want := []string{
+			store.AuditUserDeactivated, store.AuditUserActivated, store.AuditUserDeleted,
+		}
+		if len(audit.Events) != len(want) {
+			t.Fatalf("expected %d audit events but got %d", len(want), len(audit.Events))
+		}
+
+		for i, event := range audit.Events {
+			if event.Action != want[i] || event.ActorID != 1 || event.TargetID != 2 {
+				t.Errorf("unexpected audit event %+v", event)
+			}
+			if event.RequestID == "" || len(event.Before) == 0 {
+				t.Errorf("expected a request ID and a snapshot in %+v", event)
+			}
+		}
+
+		if !strings.Contains(string(audit.Events[0].After), `"deactivated_by":1`) {
+			t.Errorf("expected the admin in the deactivation snapshot but got %s", audit.Events[0].After)
+		}
+
+		if len(audit.Events[2].After) != 0 {
+			t.Errorf("expected no snapshot after a deletion but got %s", audit.Events[2].After)
+		}
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.dbStore.Users = &adminUserStore{role: store.Role{ID: 3, Name: "admin", Level: 3}}
+
+	for path, want := range map[string]int{
+		"/v1/admin/audit": http.StatusOK,
+		"/v1/admin/audit?action=post.deleted&target_id=3":     http.StatusOK,
+		"/v1/admin/audit?since=2025-01-01T00:00:00Z":          http.StatusOK,
+		"/v1/admin/audit?since=yesterday":                     http.StatusBadRequest,
+		"/v1/admin/audit?actor_id=me":                         http.StatusBadRequest,
+		"/v1/admin/audit?limit=0":                             http.StatusBadRequest,
+		"/v1/admin/audit?cursor=not-a-cursor&action=whatever": http.StatusBadRequest,
+	} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code != want {
+			t.Errorf("%s: expected response code %d but got %d", path, want, rr.Code)
+		}
+	}
+}
+
+func TestRestoreContent(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.dbStore.Users = &adminUserStore{role: store.Role{ID: 3, Name: "admin", Level: 3}}
+	audit := app.dbStore.Audit.(*store.MockAuditStore)
+
+	for path, want := range map[string]int{
+		"/v1/admin/posts/1/restore":    http.StatusOK,
+		"/v1/admin/posts/2/restore":    http.StatusNotFound,
+		"/v1/admin/comments/1/restore": http.StatusOK,
+		"/v1/admin/comments/2/restore": http.StatusNotFound,
+	} {
+		req, err := http.NewRequest(http.MethodPut, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code != want {
+			t.Errorf("%s: expected response code %d but got %d", path, want, rr.Code)
+		}
+	}
+
+	actions := map[string]bool{}
+	for _, event := range audit.Events {
+		actions[event.Action] = len(event.After) > 0
+	}
+
+	for _, action := range []string{store.AuditPostRestored, store.AuditCommentRestored} {
+		if restored, ok := actions[action]; !ok || !restored {
+			t.Errorf("expected a %s event with a snapshot", action)
+		}
+	}
+}
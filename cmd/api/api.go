@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,7 +20,8 @@ import (
 
 	"github.com/atomicmeganerd/gopher-social/docs"
 	"github.com/atomicmeganerd/gopher-social/internal/auth"
-	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/i18n"
+	"github.com/atomicmeganerd/gopher-social/internal/outbox"
 	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
@@ -38,9 +40,11 @@ type application struct {
 	dbStore       *store.Storage
 	cacheStore    *cache.Storage
 	logger        *slog.Logger
-	mailer        mailer.Client
+	outbox        *outbox.Worker
 	authenticator auth.Authenticator
-	rateLimiter   ratelimiter.Limiter
+	signingKeys   *auth.KeySet
+	i18n          *i18n.Catalog
+	rateLimiter   *ratelimiter.Policies
 }
 
 func (app *application) mount() http.Handler {
@@ -58,63 +62,149 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Timeout(httpTimeout))
 
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{app.config.frontendURL},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins: []string{app.config.frontendURL},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		ExposedHeaders: []string{
+			"Link",
+			"Retry-After",
+			"X-RateLimit-Limit",
+			"X-RateLimit-Remaining",
+			"X-RateLimit-Reset",
+		},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 
-	// Our custom middleware, make sure rate limter comes last
-	r.Use(app.RateLimiterMiddleware)
-
 	// Creating the routes is really easy with chi.
+	// NOTE: Every route group declares the rate limit policy it is limited by. Make sure the
+	// rate limiter comes after AuthTokenMiddleware so that it can limit per user.
 	r.Route("/v1", func(r chi.Router) {
 
-		// Do not use basic auth anymore due to need for graceful shutdown
-		r.Get("/health", app.healthCheckHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(app.RateLimiterMiddleware(rateLimitPolicyOps))
+
+			// Do not use basic auth anymore due to need for graceful shutdown
+			r.Get("/health", app.healthCheckHandler)
+			r.Get("/.well-known/jwks.json", app.jwksHandler)
 
-		// This is provided
-		r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
+			// This is provided
+			r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
 
-		// Swagger documentation route
-		docsUrl := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
-		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsUrl)))
+			// Swagger documentation route
+			docsUrl := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
+			r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsUrl)))
+		})
 
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.RateLimiterMiddleware(rateLimitPolicyPosts))
 			r.Post("/", app.createPostHandler)
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postContextMiddleware)
 				r.Get("/", app.getPostHandler)
 				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+				r.Post("/report", app.reportPostHandler)
 				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
-				r.Post("/comments", app.createCommentHandler)
+				r.Route("/comments", func(r chi.Router) {
+					r.Get("/", app.getCommentsHandler)
+					r.Post("/", app.createCommentHandler)
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.Use(app.commentContextMiddleware)
+						r.Get("/", app.getCommentHandler)
+						r.Patch("/", app.checkCommentOwnership("moderator", app.updateCommentHandler))
+						r.Delete("/", app.checkCommentOwnership("admin", app.deleteCommentHandler))
+						r.Post("/replies", app.createReplyHandler)
+						r.Post("/report", app.reportCommentHandler)
+					})
+				})
+				r.Put("/reactions/{kind}", app.reactToPostHandler)
+				r.Delete("/reactions/{kind}", app.removePostReactionHandler)
 			})
 		})
 
 		r.Route("/users", func(r chi.Router) {
-			r.Put("/activate/{token}", app.activateUserHandler)
+			r.With(app.RateLimiterMiddleware(rateLimitPolicyAuth)).
+				Put("/activate/{token}", app.activateUserHandler)
+			r.With(app.RateLimiterMiddleware(rateLimitPolicyAuth)).
+				Post("/activate/resend", app.resendActivationHandler)
 
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
+				r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
 
 				r.Get("/", app.getUserHandler)
+				r.Get("/followers", app.getFollowersHandler)
+				r.Get("/following", app.getFollowingHandler)
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unfollowUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
+				r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Put("/locale", app.updateLocaleHandler)
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
+			r.Get("/search", app.searchHandler)
+			r.Get("/explore", app.exploreHandler)
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
+			r.Get("/trending", app.getTrendingTagsHandler)
+			r.Route("/{tag}", func(r chi.Router) {
+				r.Use(app.tagContextMiddleware)
+				r.Get("/", app.getTagHandler)
+				r.Get("/posts", app.getTagPostsHandler)
+				r.Put("/follow", app.followTagHandler)
+				r.Put("/unfollow", app.unfollowTagHandler)
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
+			r.Use(app.requireRole("admin"))
+
+			r.Get("/audit", app.listAuditEventsHandler)
+			r.Get("/users", app.listUsersHandler)
+			r.Route("/users/{userID}", func(r chi.Router) {
+				r.Use(app.accountContextMiddleware)
+				r.Put("/role", app.updateUserRoleHandler)
+				r.Put("/activate", app.activateAccountHandler)
+				r.Put("/deactivate", app.deactivateAccountHandler)
+				r.Delete("/", app.deleteAccountHandler)
 			})
+			r.Put("/posts/{postID}/restore", app.restorePostHandler)
+			r.Put("/comments/{commentID}/restore", app.restoreCommentHandler)
+		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.RateLimiterMiddleware(ratelimiter.DefaultPolicy))
+			r.Use(app.requireRole("moderator"))
+
+			r.Get("/reports", app.listReportsHandler)
+			r.Post("/reports/{reportID}/claim", app.claimReportHandler)
+			r.Post("/reports/{reportID}/resolve", app.resolveReportHandler)
 		})
 
 		// routes
 		r.Route("/authentication", func(r chi.Router) {
+			r.Use(app.RateLimiterMiddleware(rateLimitPolicyAuth))
 			r.Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.With(app.AuthTokenMiddleware).Post("/logout", app.logoutHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Post("/password/reset", app.resetPasswordHandler)
 		})
 	})
 
@@ -137,6 +227,23 @@ func (app *application) run(mux http.Handler) error {
 
 	shutdown := make(chan error)
 
+	// The background workers stop with the server, emails the outbox worker did not get to are
+	// sent on the next start
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	workers.Go(func() {
+		if app.outbox != nil {
+			app.outbox.Run(workerCtx)
+		}
+	})
+	workers.Go(func() {
+		app.runPurge(workerCtx)
+	})
+	workers.Go(func() {
+		app.runExploreRefresh(workerCtx)
+	})
+
 	go func() {
 
 		quit := make(chan os.Signal, 1)
@@ -163,6 +270,8 @@ func (app *application) run(mux http.Handler) error {
 	}
 
 	err = <-shutdown
+	stopWorkers()
+	workers.Wait()
 	if err != nil {
 		app.logger.Error("Unexpected error...", "error", err)
 		return err
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -43,6 +44,19 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		}
 		defer resp.Body.Close() // nolint: errcheck
 
+		if limit := resp.Header.Get("X-RateLimit-Limit"); limit != "20" {
+			t.Errorf("expected X-RateLimit-Limit 20; got %q", limit)
+		}
+
+		remaining := max(cfg.rateLimiter.RequestsPerTimeFrame-i-1, 0)
+		if got := resp.Header.Get("X-RateLimit-Remaining"); got != strconv.Itoa(remaining) {
+			t.Errorf("expected X-RateLimit-Remaining %d; got %q", remaining, got)
+		}
+
+		if resp.Header.Get("X-RateLimit-Reset") == "" {
+			t.Error("expected X-RateLimit-Reset to be set")
+		}
+
 		if i < cfg.rateLimiter.RequestsPerTimeFrame {
 			if resp.StatusCode != http.StatusOK {
 				t.Errorf("expected status OK; got %v", resp.Status)
@@ -54,3 +68,66 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		}
 	}
 }
+
+func TestRateLimiterMiddlewareIgnoresPort(t *testing.T) {
+	cfg := config{
+		rateLimiter: ratelimiter.Config{
+			RequestsPerTimeFrame: 2,
+			TimeFrame:            time.Minute,
+			Enabled:              true,
+		},
+	}
+
+	app := newTestApp(t, cfg)
+	mux := app.mount()
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req, err := http.NewRequest(http.MethodGet, "/v1/health", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A new connection of the same client
+		req.RemoteAddr = fmt.Sprintf("10.0.0.1:%d", 40000+i)
+
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, want, rr.Code)
+	}
+}
+
+func TestRateLimiterMiddlewarePerUser(t *testing.T) {
+
+	cfg := config{
+		rateLimiter: ratelimiter.Config{
+			RequestsPerTimeFrame: 3,
+			TimeFrame:            time.Second * 5,
+			Enabled:              true,
+		},
+		addr: ":8080",
+	}
+
+	app := newTestApp(t, cfg)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same user from a different address every time still shares one budget
+	for i := range cfg.rateLimiter.RequestsPerTimeFrame + 1 {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", i+1)
+		rr := execMockRequests(req, mux)
+
+		if i < cfg.rateLimiter.RequestsPerTimeFrame {
+			checkResponseCode(t, http.StatusOK, rr.Code)
+		} else {
+			checkResponseCode(t, http.StatusTooManyRequests, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// recordAudit records a change made by the authenticated user with snapshots of the target before
+// and after it, nil snapshots are left empty. The change is already done when this runs so a
+// failure does not fail the request, the event is logged instead so it is not lost.
+func (app *application) recordAudit(
+	r *http.Request, action, targetType string, targetID int64, before, after any,
+) {
+	event := &store.AuditEvent{
+		ActorID:    getUserFromContext(r).ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		RequestID:  middleware.GetReqID(r.Context()),
+		IP:         r.RemoteAddr,
+	}
+
+	var err error
+	if event.Before, err = snapshot(before); err == nil {
+		event.After, err = snapshot(after)
+	}
+
+	if err == nil {
+		// The request may be canceled once the response is written, the event must still be stored
+		ctx := context.WithoutCancel(r.Context())
+		err = app.dbStore.Audit.Record(ctx, event)
+	}
+
+	if err != nil {
+		app.logger.Error(
+			"failed to record audit event",
+			"error", err,
+			"actorID", event.ActorID,
+			"action", event.Action,
+			"targetType", event.TargetType,
+			"targetID", event.TargetID,
+			"requestID", event.RequestID,
+		)
+	}
+}
+
+func snapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+// ListAuditEvents godoc
+//
+//	@Summary		Lists the audit trail
+//	@Description	Lists the privileged and destructive changes, newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			actor_id	query		int		false	"User who made the change"
+//	@Param			action		query		string	false	"Action such as post.deleted"
+//	@Param			target_type	query		string	false	"Target type such as post or user"
+//	@Param			target_id	query		int		false	"Target ID"
+//	@Param			since		query		string	false	"RFC3339 time"
+//	@Param			until		query		string	false	"RFC3339 time"
+//	@Param			cursor		query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200			{object}	[]store.AuditEvent
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit [get]
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	aq := store.PaginatedAuditQuery{
+		Limit: 50,
+	}
+
+	aq, err := aq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(aq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	events, err := app.dbStore.Audit.List(r.Context(), aq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, err := store.NextAuditCursor(events, aq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, events, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
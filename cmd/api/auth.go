@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -8,6 +10,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/atomicmeganerd/gopher-social/internal/auth"
 	"github.com/atomicmeganerd/gopher-social/internal/mailer"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/golang-jwt/jwt/v5"
@@ -18,6 +21,8 @@ type RegisteredUserPayload struct {
 	Username string `json:"username" validate:"required,max=100"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=8,max=72"`
+	// Negotiated from the Accept-Language header when not set
+	Locale string `json:"locale" validate:"omitempty,max=10"`
 }
 
 type UserWithToken struct {
@@ -50,11 +55,20 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	locale := payload.Locale
+	if locale == "" {
+		locale = app.locale(r)
+	} else if !app.i18n.Supported(locale) {
+		app.badRequestError(w, r, errUnsupportedLocale)
+		return
+	}
+
 	// Hash the password before storing it
 
 	user := &store.User{
 		Username: payload.Username,
 		Email:    payload.Email,
+		Locale:   locale,
 		Role: store.Role{
 			Name: "user",
 		},
@@ -67,11 +81,18 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	plainToken := uuid.New().String()
 	// Hash the token to store in the database (this encrypts it)
-	hash := sha256.Sum256([]byte(plainToken))
-	hashToken := hex.EncodeToString(hash[:])
+	tokenHash := hashToken(plainToken)
+
+	// The invitation is delivered by the outbox worker, registering does not wait for the mail
+	// provider and does not fail when it is down
+	invitation, err := app.invitationEmail(user, plainToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.dbStore.Users.CreateAndInvite(
-		r.Context(), user, hashToken, app.config.mail.exp,
+		r.Context(), user, tokenHash, app.config.mail.exp, invitation,
 	); err != nil {
 		switch err {
 		case store.ErrDuplicateUsername:
@@ -89,6 +110,15 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Token: plainToken,
 	}
 
+	if err := app.jsonResponse(w, http.StatusCreated, userWithToken); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// invitationEmail is the email with the activation link of an invitation
+func (app *application) invitationEmail(
+	user *store.User, plainToken string,
+) (*store.OutboxEmail, error) {
 	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, plainToken)
 	isProdEnv := app.config.env == "production"
 	vars := struct {
@@ -99,24 +129,9 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		ActivationURL: activationURL,
 	}
 
-	status, err := app.mailer.Send(
-		mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv,
+	return store.NewOutboxEmail(
+		mailer.UserWelcomeTemplate, user.Locale, user.Username, user.Email, vars, !isProdEnv,
 	)
-
-	if err != nil {
-		if err := app.dbStore.Users.Delete(r.Context(), user.ID); err != nil {
-			app.logger.Error("error deleting user", "user", user)
-			return
-		}
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	app.logger.Info("Email sent", "status code", status)
-
-	if err := app.jsonResponse(w, http.StatusCreated, userWithToken); err != nil {
-		app.internalServerError(w, r, err)
-	}
 }
 
 type CreateUserTokenPayload struct {
@@ -124,15 +139,31 @@ type CreateUserTokenPayload struct {
 	Password string `json:"password" validate:"required,min=8,max=72"`
 }
 
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=64"`
+}
+
+// TokenPair is a short lived access token with the refresh token used to renew it
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type claimsKey string
+
+const claimsCtx claimsKey = "claims"
+
 // createTokenHandler godoc
 //
 //	@Summary		Creates a token
-//	@Description	Creates a token for a user
+//	@Description	Starts a session for a user and returns an access token and a refresh token
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
-//	@Success		200		{string}	string					"Token"
+//	@Success		201		{object}	TokenPair				"Tokens"
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
 //	@Failure		500		{object}	error
@@ -150,7 +181,8 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Check that jwt is configured
-	if app.config.auth.jwtToken.tokenHost == "" || app.config.auth.jwtToken.secret == "" {
+	jwtConfig := app.config.auth.jwtToken
+	if jwtConfig.tokenHost == "" || (jwtConfig.secret == "" && jwtConfig.keysDir == "") {
 		app.internalServerError(w, r, errors.New("jwt is not configured on this app instance"))
 		return
 	}
@@ -164,31 +196,205 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		default:
 			app.internalServerError(w, r, err)
 		}
-
+		return
 	}
 
 	if err := user.Password.Compare(payload.Password); err != nil {
 		app.unauthorizedError(w, r, errors.New("invalid password"))
+		return
+	}
+
+	session := &store.Session{
+		ID:     uuid.New().String(),
+		UserID: user.ID,
+	}
+	refreshToken := rand.Text()
+
+	if err := app.dbStore.Sessions.Create(
+		r.Context(), session, hashToken(refreshToken), app.config.auth.jwtToken.refreshExpiry,
+	); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := app.newTokenPair(session, refreshToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// send the tokens to the client
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refreshes a token
+//	@Description	Exchanges a refresh token for a new access token and refresh token. Each refresh
+//	@Description	token can only be used once, reusing one ends its session.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{object}	TokenPair			"Tokens"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	refreshToken := rand.Text()
+	session, err := app.dbStore.Sessions.Rotate(
+		r.Context(), hashToken(payload.RefreshToken), hashToken(refreshToken),
+	)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedError(w, r, errors.New("invalid refresh token"))
+		case store.ErrTokenReused:
+			app.logger.Warn("refresh token reused, its session was revoked")
+			app.unauthorizedError(w, r, errors.New("invalid refresh token"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Users that were deactivated or deleted cannot refresh their tokens
+	if _, err := app.getUser(r.Context(), session.UserID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	tokens, err := app.newTokenPair(session, refreshToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// logoutHandler godoc
+//
+//	@Summary		Logs out
+//	@Description	Revokes the access token and ends its session, so its refresh token is no
+//	@Description	longer valid either
+//	@Tags			authentication
+//	@Success		204	"Logged out"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/authentication/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	claims := getClaimsFromContext(r)
+	if err := app.revokeToken(r.Context(), claims); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) revokeToken(ctx context.Context, claims jwt.MapClaims) error {
+	if sessionID, ok := claims["sid"].(string); ok {
+		if err := app.dbStore.Sessions.Revoke(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return nil
 	}
 
+	expiry, err := claims.GetExpirationTime()
+	if err != nil || expiry == nil {
+		return errors.New("token has no expiry")
+	}
+
+	return app.dbStore.Sessions.RevokeToken(ctx, jti, expiry.Time)
+}
+
+// newTokenPair signs a new access token for the session and pairs it with a refresh token
+func (app *application) newTokenPair(session *store.Session, refreshToken string) (*TokenPair, error) {
+	expiry := app.config.auth.jwtToken.expiry
+
 	// NOTE: Check the docs on JWT to see what claims can be setup
 	// See: https://auth0.com/docs/secure/tokens/json-web-tokens/json-web-token-claims
 	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(app.config.auth.jwtToken.expiry).Unix(),
+		"sub": session.UserID,
+		"sid": session.ID,
+		"jti": uuid.New().String(),
+		"exp": time.Now().Add(expiry).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
 		"iss": app.config.auth.jwtToken.tokenHost,
 		"aud": app.config.auth.jwtToken.tokenHost,
 	}
-	token, err := app.authenticator.GenerateToken(claims)
+	accessToken, err := app.authenticator.GenerateToken(claims)
 	if err != nil {
-		app.internalServerError(w, r, err)
-		return
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(expiry.Seconds()),
+	}, nil
+}
+
+// jwksHandler godoc
+//
+//	@Summary		JSON Web Key Set
+//	@Description	Publishes the public keys access tokens are signed with so other services can
+//	@Description	verify them. The set is empty when tokens are signed with a shared secret.
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKS
+//	@Router			/.well-known/jwks.json [get]
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	jwks := auth.JWKS{Keys: []auth.JWK{}}
+	if app.signingKeys != nil {
+		jwks = app.signingKeys.JWKS()
 	}
 
-	// send the token to the client
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	// Verifiers cache the keys, a rotation must add a key well before it becomes active
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	// The key set is served as is (RFC 7517) rather than in the data envelope
+	if err := writeJSON(w, http.StatusOK, jwks); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// hashToken hashes the tokens sent to users so they are not stored in plain text
+func hashToken(plainToken string) string {
+	hash := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func getClaimsFromContext(r *http.Request) jwt.MapClaims {
+	claims, _ := r.Context().Value(claimsCtx).(jwt.MapClaims)
+	return claims
+}
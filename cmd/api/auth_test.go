@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestRefreshToken(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	t.Run("should reject requests without a refresh token", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost, "/v1/authentication/refresh", strings.NewReader(`{}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should issue a new token pair", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/v1/authentication/refresh",
+			strings.NewReader(`{"refresh_token": "some-refresh-token"}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		for _, field := range []string{"access_token", "refresh_token", "expires_in"} {
+			if !strings.Contains(rr.Body.String(), field) {
+				t.Errorf("expected %q in the response but got %s", field, rr.Body.String())
+			}
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should end the session", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	t.Run("should email a reset link", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/v1/authentication/password/forgot",
+			strings.NewReader(`{"email": "gopher@example.com"}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+		enqueued := app.dbStore.Outbox.(*store.MockOutboxStore).Enqueued
+		if len(enqueued) != 1 {
+			t.Fatalf("expected a reset email but got %+v", enqueued)
+		}
+		email := enqueued[0]
+		if email.Template != mailer.PasswordResetTemplate {
+			t.Errorf("expected the reset template but got %s", email.Template)
+		}
+		if email.Email != "gopher@example.com" {
+			t.Errorf("expected the email to go to gopher@example.com but got %s", email.Email)
+		}
+
+		var data struct{ ResetURL string }
+		if err := json.Unmarshal(email.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		_, token, _ := strings.Cut(data.ResetURL, "/reset-password/")
+		if token == "" {
+			t.Errorf("expected a reset token in the link but got %q", data.ResetURL)
+		}
+	})
+
+	t.Run("should reject short passwords", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/v1/authentication/password/reset",
+			strings.NewReader(`{"token": "some-token", "password": "short"}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reset the password", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/v1/authentication/password/reset",
+			strings.NewReader(`{"token": "some-token", "password": "a-new-password"}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
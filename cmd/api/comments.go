@@ -1,19 +1,73 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
+const commentCtx commentKey = "comment"
+
+type commentKey string
+
 type CreateCommentPayload struct {
 	Content string `json:"content" validate:"required,max=1000"`
 }
 
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+// CreateComment godoc
+//
+//	@Summary		Creates a comment
+//	@Description	Creates a top level comment on a post
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int						true	"Post ID"
+//	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
+//	@Success		201		{object}	store.Comment
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments [post]
 func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+	app.createComment(w, r, post.ID, nil)
+}
 
-	user := getUserFromContext(r)
+// CreateReply godoc
+//
+//	@Summary		Replies to a comment
+//	@Description	Creates a reply to a comment on a post
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			payload		body		CreateCommentPayload	true	"Comment payload"
+//	@Success		201			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/replies [post]
+func (app *application) createReplyHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
+	parent := getCommentFromContext(r)
+	app.createComment(w, r, post.ID, &parent.ID)
+}
+
+func (app *application) createComment(
+	w http.ResponseWriter, r *http.Request, postID int64, parentID *int64,
+) {
+	user := getUserFromContext(r)
 
 	var payload CreateCommentPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -26,13 +80,22 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	entities, mentioned, err := app.resolveEntities(r.Context(), payload.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	comment := &store.Comment{
-		PostID:  post.ID,
-		UserID:  user.ID,
-		Content: payload.Content,
+		PostID:   postID,
+		UserID:   user.ID,
+		ParentID: parentID,
+		Content:  payload.Content,
+		Entities: entities,
 	}
 
-	if err := app.dbStore.Comments.Create(r.Context(), comment); err != nil {
+	mentionEmail := app.mentionEmail(r, mentioned, comment.Content)
+	if err := app.dbStore.Comments.Create(r.Context(), comment, mentionEmail); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -41,3 +104,218 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		app.internalServerError(w, r, err)
 	}
 }
+
+// GetComments godoc
+//
+//	@Summary		Fetches the comments of a post
+//	@Description	Fetches the comment threads of a post as a tree
+//	@Tags			comments
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			parent_id	query		int	false	"Only return the replies of this comment"
+//	@Param			depth		query		int	false	"Levels of replies to return"
+//	@Param			limit		query		int	false	"Comments per level"
+//	@Param			offset		query		int	false	"Offset of the top level"
+//	@Success		200			{object}	[]store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments [get]
+func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	cq, err := app.parseCommentQuery(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	comments, err := app.dbStore.Comments.GetByPostID(r.Context(), post.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, comments); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// parseCommentQuery reads the comment tree pagination from the query string. The requested
+// depth is capped to the configured maximum so a client cannot load a whole thread at once.
+func (app *application) parseCommentQuery(r *http.Request) (store.PaginatedCommentQuery, error) {
+	cq := store.PaginatedCommentQuery{
+		Limit:  app.config.comments.pageSize,
+		Offset: 0,
+		Depth:  app.config.comments.maxDepth,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		return cq, err
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		return cq, err
+	}
+
+	cq.Depth = min(cq.Depth, app.config.comments.maxDepth)
+	return cq, nil
+}
+
+func (app *application) commentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		comment, err := app.dbStore.Comments.GetByID(ctx, commentID)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		// The comment must belong to the post in the URL
+		post := getPostFromContext(r)
+		if post == nil || comment.PostID != post.ID {
+			app.notFoundError(w, r, errors.New("comment does not belong to the post"))
+			return
+		}
+
+		ctx = context.WithValue(ctx, commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetComment godoc
+//
+//	@Summary		Fetches a comment
+//	@Description	Fetches a comment of a post by ID, without its replies
+//	@Tags			comments
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [get]
+func (app *application) getCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromContext(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateComment godoc
+//
+//	@Summary		Updates a comment
+//	@Description	Updates a comment, its author and the moderators can edit it
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			payload		body		UpdateCommentPayload	true	"Comment payload"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromContext(r)
+	before := *comment
+
+	var payload UpdateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	entities, mentioned, err := app.resolveEntities(r.Context(), payload.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	comment.Content = payload.Content
+	comment.Entities = entities
+
+	mentionEmail := app.mentionEmail(r, mentioned, comment.Content)
+	if err := app.dbStore.Comments.Update(r.Context(), comment, mentionEmail); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(
+		r, store.AuditCommentUpdated, store.AuditTargetComment, comment.ID, before, comment,
+	)
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteComment godoc
+//
+//	@Summary		Deletes a comment
+//	@Description	Deletes a comment and hides its replies, its author and the admins can delete it
+//	@Tags			comments
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		204			{object}	string
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromContext(r)
+
+	err := app.dbStore.Comments.Delete(r.Context(), comment.ID, getUserFromContext(r).ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(
+		r, store.AuditCommentDeleted, store.AuditTargetComment, comment.ID, comment, nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getCommentFromContext(r *http.Request) *store.Comment {
+	comment, _ := r.Context().Value(commentCtx).(*store.Comment)
+	return comment
+}
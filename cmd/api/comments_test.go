@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// otherAuthorCommentStore has the comments of the mock store written by another user
+type otherAuthorCommentStore struct {
+	store.MockCommentStore
+}
+
+func (s *otherAuthorCommentStore) GetByID(
+	ctx context.Context, commentID int64,
+) (*store.Comment, error) {
+	comment, err := s.MockCommentStore.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	comment.UserID = 2
+	return comment, nil
+}
+
+func TestCommentOwnership(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path string) int {
+		req, err := http.NewRequest(method, path, strings.NewReader(`{"content": "Edited"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux).Code
+	}
+
+	t.Run("should let the author manage the comment", func(t *testing.T) {
+		app.dbStore.Users = &adminUserStore{role: store.Role{ID: 1, Name: "user", Level: 1}}
+
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/posts/1/comments/1"))
+		checkResponseCode(t, http.StatusOK, request(http.MethodPatch, "/v1/posts/1/comments/1"))
+		checkResponseCode(
+			t, http.StatusNoContent, request(http.MethodDelete, "/v1/posts/1/comments/1"),
+		)
+		checkResponseCode(t, http.StatusNotFound, request(http.MethodGet, "/v1/posts/1/comments/2"))
+	})
+
+	app.dbStore.Comments = &otherAuthorCommentStore{}
+
+	for _, tc := range []struct {
+		role         store.Role
+		updateStatus int
+		deleteStatus int
+	}{
+		{store.Role{ID: 1, Name: "user", Level: 1}, http.StatusForbidden, http.StatusForbidden},
+		{store.Role{ID: 2, Name: "moderator", Level: 2}, http.StatusOK, http.StatusForbidden},
+		{store.Role{ID: 3, Name: "admin", Level: 3}, http.StatusOK, http.StatusNoContent},
+	} {
+		t.Run("should check the role of "+tc.role.Name, func(t *testing.T) {
+			app.dbStore.Users = &adminUserStore{role: tc.role}
+
+			checkResponseCode(
+				t, tc.updateStatus, request(http.MethodPatch, "/v1/posts/1/comments/1"),
+			)
+			checkResponseCode(
+				t, tc.deleteStatus, request(http.MethodDelete, "/v1/posts/1/comments/1"),
+			)
+		})
+	}
+}
+
+// threadCommentStore records the comment queries and adds a comment with ID 2 on the post 2
+type threadCommentStore struct {
+	store.MockCommentStore
+	queries []store.PaginatedCommentQuery
+}
+
+func (s *threadCommentStore) GetByID(
+	ctx context.Context, commentID int64,
+) (*store.Comment, error) {
+	if commentID == 2 {
+		return &store.Comment{ID: 2, PostID: 2, UserID: 1, Content: "Nice crabs"}, nil
+	}
+	return s.MockCommentStore.GetByID(ctx, commentID)
+}
+
+func (s *threadCommentStore) GetByPostID(
+	ctx context.Context, postID int64, cq store.PaginatedCommentQuery,
+) ([]store.Comment, error) {
+	s.queries = append(s.queries, cq)
+	return s.MockCommentStore.GetByPostID(ctx, postID, cq)
+}
+
+func TestCommentThreads(t *testing.T) {
+	app := newTestApp(t, config{
+		comments: commentsConfig{maxDepth: 3, pageSize: 20},
+	})
+	mux := app.mount()
+
+	comments := &threadCommentStore{}
+	app.dbStore.Comments = comments
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux)
+	}
+
+	t.Run("should page the comment threads", func(t *testing.T) {
+		for query, want := range map[string]store.PaginatedCommentQuery{
+			"":                          {Limit: 20, Offset: 0, Depth: 3},
+			"?depth=2":                  {Limit: 20, Offset: 0, Depth: 2},
+			"?depth=10":                 {Limit: 20, Offset: 0, Depth: 3},
+			"?limit=2&offset=4&depth=1": {Limit: 2, Offset: 4, Depth: 1},
+		} {
+			comments.queries = nil
+			rr := request(http.MethodGet, "/v1/posts/1/comments"+query, "")
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if len(comments.queries) != 1 {
+				t.Fatalf("%q: expected a single query but got %v", query, comments.queries)
+			}
+			if got := comments.queries[0]; got.Limit != want.Limit ||
+				got.Offset != want.Offset || got.Depth != want.Depth {
+				t.Errorf("%q: expected the query %+v but got %+v", query, want, got)
+			}
+		}
+	})
+
+	t.Run("should reject invalid pages", func(t *testing.T) {
+		for _, query := range []string{"depth=0", "limit=0", "limit=51", "offset=-1", "limit=a"} {
+			rr := request(http.MethodGet, "/v1/posts/1/comments?"+query, "")
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf(
+					"%q: expected response code %d but got %d", query, http.StatusBadRequest, rr.Code,
+				)
+			}
+		}
+	})
+
+	t.Run("should reply to a comment of the post", func(t *testing.T) {
+		rr := request(http.MethodPost, "/v1/posts/1/comments/1/replies", `{"content": "Agreed"}`)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var body struct {
+			Data store.Comment `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.ParentID == nil || *body.Data.ParentID != 1 || body.Data.PostID != 1 {
+			t.Errorf("expected a reply to the comment 1 of the post 1 but got %+v", body.Data)
+		}
+	})
+
+	t.Run("should not reply to other comments", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/v1/posts/1/comments/2/replies":    http.StatusNotFound,
+			"/v1/posts/1/comments/99/replies":   http.StatusNotFound,
+			"/v1/posts/1/comments/nope/replies": http.StatusBadRequest,
+		} {
+			rr := request(http.MethodPost, path, `{"content": "Agreed"}`)
+			if rr.Code != want {
+				t.Errorf("%s: expected response code %d but got %d", path, want, rr.Code)
+			}
+		}
+	})
+}
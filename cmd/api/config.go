@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/atomicmeganerd/gopher-social/internal/env"
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/outbox"
 	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 )
 
@@ -21,6 +23,10 @@ type config struct {
 	mail              mailConfig
 	auth              authConfig
 	rateLimiter       ratelimiter.Config
+	outbox            outbox.Config
+	comments          commentsConfig
+	purge             purgeConfig
+	explore           exploreConfig
 }
 
 func NewConfig() config {
@@ -44,11 +50,22 @@ func NewConfig() config {
 			enabled:  env.GetBool("REDIS_ENABLE", false),
 		},
 		mail: mailConfig{
-			exp:       time.Hour * 24 * 3, // 3 days
-			fromEmail: env.GetString("FROM_EMAIL", ""),
+			backend: env.GetString("MAIL_BACKEND", mailer.BackendSendGrid),
+			exp:     time.Hour * 24 * 3, // 3 days
+			// Password reset links are much shorter lived than invitations
+			passwordResetExp: time.Hour * 1,
+			fromEmail:        env.GetString("FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", ""),
 			},
+			smtp: smtpConfig{
+				host:     env.GetString("SMTP_HOST", "localhost"),
+				port:     env.GetInt("SMTP_PORT", 1025),
+				username: env.GetString("SMTP_USERNAME", ""),
+				password: env.GetString("SMTP_PASSWORD", ""),
+			},
+			// The file backend writes to stdout when no directory is set
+			dir: env.GetString("MAIL_DIR", ""),
 		},
 		auth: authConfig{
 			basic: basicAuthConfig{
@@ -58,13 +75,66 @@ func NewConfig() config {
 			jwtToken: jwtTokenConfig{
 				secret:    env.GetString("JWT_SECRET", ""),
 				tokenHost: env.GetString("JWT_TOKEN_HOST", ""),
-				expiry:    time.Hour * 24 * 3, // 3 days
+				// When set, tokens are signed with the RSA or Ed25519 keys of this directory
+				// instead of the shared secret
+				keysDir:     env.GetString("JWT_KEYS_DIR", ""),
+				activeKeyID: env.GetString("JWT_ACTIVE_KEY_ID", ""),
+				// Access tokens are short lived, clients use their refresh token to get a new one
+				expiry:        time.Minute * 15,
+				refreshExpiry: time.Hour * 24 * 30, // 30 days
 			},
 		},
 		rateLimiter: ratelimiter.Config{
 			RequestsPerTimeFrame: env.GetInt("RL_REQUESTS_COUNT", 50),
 			TimeFrame:            time.Second * 1,
 			Enabled:              env.GetBool("RL_ENABLED", true),
+			Backend:              env.GetString("RL_BACKEND", ratelimiter.BackendMemory),
+			Algorithm:            env.GetString("RL_ALGORITHM", ratelimiter.AlgorithmFixedWindow),
+			Policies: map[string]ratelimiter.Policy{
+				// Health checks and docs are cheap
+				rateLimitPolicyOps: {
+					RequestsPerTimeFrame: env.GetInt("RL_OPS_REQUESTS_COUNT", 100),
+					TimeFrame:            time.Second * 1,
+				},
+				// Posts, comments and reactions write to the database
+				rateLimitPolicyPosts: {
+					RequestsPerTimeFrame: env.GetInt("RL_POSTS_REQUESTS_COUNT", 20),
+					TimeFrame:            time.Second * 1,
+				},
+				// Keep brute forcing of passwords and tokens slow
+				rateLimitPolicyAuth: {
+					RequestsPerTimeFrame: env.GetInt("RL_AUTH_REQUESTS_COUNT", 10),
+					TimeFrame:            time.Minute * 1,
+				},
+				// Invitations sent again to the same email address
+				rateLimitPolicyResend: {
+					RequestsPerTimeFrame: env.GetInt("RL_RESEND_REQUESTS_COUNT", 3),
+					TimeFrame:            time.Hour * 1,
+				},
+			},
+			AdminMultiplier: env.GetInt("RL_ADMIN_MULTIPLIER", 5),
+			// Matches the admin level seeded in the roles table
+			AdminLevel: env.GetInt("RL_ADMIN_LEVEL", 3),
+		},
+		outbox: outbox.Config{
+			PollInterval: time.Second * time.Duration(env.GetInt("OUTBOX_POLL_INTERVAL_SECONDS", 5)),
+			BatchSize:    env.GetInt("OUTBOX_BATCH_SIZE", 10),
+			MaxAttempts:  env.GetInt("OUTBOX_MAX_ATTEMPTS", 8),
+			BaseBackoff:  time.Second * 30,
+			MaxBackoff:   time.Hour * 1,
+		},
+		comments: commentsConfig{
+			maxDepth: env.GetInt("COMMENTS_MAX_DEPTH", 5),
+			pageSize: env.GetInt("COMMENTS_PAGE_SIZE", 20),
+		},
+		explore: exploreConfig{
+			window:  time.Hour * 24 * time.Duration(env.GetInt("EXPLORE_WINDOW_DAYS", 7)),
+			refresh: time.Minute * time.Duration(env.GetInt("EXPLORE_REFRESH_MINUTES", 5)),
+			size:    env.GetInt("EXPLORE_SIZE", 500),
+		},
+		purge: purgeConfig{
+			retention: time.Hour * 24 * time.Duration(env.GetInt("DELETED_RETENTION_DAYS", 30)),
+			interval:  time.Minute * time.Duration(env.GetInt("PURGE_INTERVAL_MINUTES", 60)),
 		},
 	}
 }
@@ -82,21 +152,35 @@ type basicAuthConfig struct {
 }
 
 type jwtTokenConfig struct {
-	secret    string // WARNING: Sensitive secret, do not expose
-	tokenHost string
-	expiry    time.Duration
+	secret        string // WARNING: Sensitive secret, do not expose
+	tokenHost     string
+	keysDir       string
+	activeKeyID   string
+	expiry        time.Duration
+	refreshExpiry time.Duration
 }
 
 type mailConfig struct {
-	sendGrid  sendGridConfig
-	fromEmail string
-	exp       time.Duration
+	backend          string
+	sendGrid         sendGridConfig
+	smtp             smtpConfig
+	dir              string
+	fromEmail        string
+	exp              time.Duration
+	passwordResetExp time.Duration
 }
 
 type sendGridConfig struct {
 	apiKey string
 }
 
+type smtpConfig struct {
+	host     string
+	port     int
+	username string
+	password string // WARNING: Sensitive secret, do not expose
+}
+
 type dbConfig struct {
 	addr         string
 	maxOpenConns int
@@ -110,3 +194,19 @@ type cacheConfig struct {
 	db       int
 	enabled  bool
 }
+
+type commentsConfig struct {
+	maxDepth int // The deepest level of replies returned in a comment tree
+	pageSize int // The default number of comments returned per level
+}
+
+type purgeConfig struct {
+	retention time.Duration // How long deleted posts and comments can be restored
+	interval  time.Duration // How often the expired posts and comments are purged
+}
+
+type exploreConfig struct {
+	window  time.Duration // How old the trending posts can be
+	refresh time.Duration // How often the trending posts are ranked again
+	size    int           // How many trending posts are ranked
+}
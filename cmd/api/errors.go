@@ -1,10 +1,12 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 )
 
+var errUnsupportedLocale = errors.New("unsupported locale")
+
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Error(
 		"internal server error",
@@ -15,7 +17,7 @@ func (app *application) internalServerError(w http.ResponseWriter, r *http.Reque
 	writeJSONError(
 		w,
 		http.StatusInternalServerError,
-		"the server encountered a problem and could not process your request",
+		app.translate(w, r, "error.internal"),
 	)
 }
 
@@ -43,7 +45,7 @@ func (app *application) notFoundError(w http.ResponseWriter, r *http.Request, er
 	writeJSONError(
 		w,
 		http.StatusNotFound,
-		"resource not found",
+		app.translate(w, r, "error.not_found"),
 	)
 }
 
@@ -57,7 +59,7 @@ func (app *application) conflictError(w http.ResponseWriter, r *http.Request, er
 	writeJSONError(
 		w,
 		http.StatusConflict,
-		"resource conflict",
+		app.translate(w, r, "error.conflict"),
 	)
 }
 
@@ -66,7 +68,7 @@ func (app *application) unauthorizedError(w http.ResponseWriter, r *http.Request
 		"unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err.Error(),
 	)
 
-	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	writeJSONError(w, http.StatusUnauthorized, app.translate(w, r, "error.unauthorized"))
 }
 
 func (app *application) unauthorizedBasicError(w http.ResponseWriter, r *http.Request, err error) {
@@ -77,13 +79,13 @@ func (app *application) unauthorizedBasicError(w http.ResponseWriter, r *http.Re
 	// Set the WWW-Authenticate header to indicate that basic authentication is required
 	// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/WWW-Authenticate
 	w.Header().Set("WWW-Authenticate", `Basic realm="Restricted", charset="UTF-8"`)
-	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	writeJSONError(w, http.StatusUnauthorized, app.translate(w, r, "error.unauthorized"))
 }
 
 func (app *application) forbiddenError(w http.ResponseWriter, r *http.Request) {
 	app.logger.Warn("forbidden error", "method", r.Method, "path", r.URL.Path)
 
-	writeJSONError(w, http.StatusForbidden, "forbidden")
+	writeJSONError(w, http.StatusForbidden, app.translate(w, r, "error.forbidden"))
 }
 
 func (app *application) rateLimitExceeededError(
@@ -99,6 +101,8 @@ func (app *application) rateLimitExceeededError(
 
 	w.Header().Set("Retry-After", retryAfter)
 	writeJSONError(
-		w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry after: %s", retryAfter),
+		w,
+		http.StatusTooManyRequests,
+		app.translate(w, r, "error.rate_limit_exceeded", retryAfter),
 	)
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// How long the ranking is cached at least, when the refreshes are disabled it is ranked again on
+// the first request after it expires.
+const minExploreExpiry = time.Minute
+
+// Explore godoc
+//
+//	@Summary		Fetches the explore feed
+//	@Description	Fetches the trending recent posts of all users, ranked by comments and reactions
+//	@Description	with newer posts ranking higher. The ranking is refreshed every few minutes.
+//	@Tags			feed
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			tags	query		string	false	"Tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	[]store.TrendingPost
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/explore [get]
+func (app *application) exploreHandler(w http.ResponseWriter, r *http.Request) {
+	eq := store.PaginatedExploreQuery{
+		Limit: 20,
+	}
+
+	eq, err := eq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(eq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	posts, err := app.getTrending(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, eq.Page(posts)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTrending returns the cached ranking, it is computed on the spot when the cache is disabled
+// or before the first refresh.
+func (app *application) getTrending(ctx context.Context) ([]store.TrendingPost, error) {
+	if !app.config.cache.enabled {
+		return app.dbStore.Posts.GetTrending(
+			ctx, app.config.explore.window, app.config.explore.size,
+		)
+	}
+
+	posts, err := app.cacheStore.Explore.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if posts == nil {
+		app.logger.Debug("cache miss, ranking trending posts")
+		return app.refreshTrending(ctx)
+	}
+
+	return posts, nil
+}
+
+// refreshTrending ranks the trending posts and caches the ranking
+func (app *application) refreshTrending(ctx context.Context) ([]store.TrendingPost, error) {
+	posts, err := app.dbStore.Posts.GetTrending(
+		ctx, app.config.explore.window, app.config.explore.size,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep serving the ranking for a while if the refreshes stop
+	expiry := max(2*app.config.explore.refresh, minExploreExpiry)
+	if err := app.cacheStore.Explore.Set(ctx, posts, expiry); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// trendingChanged evicts the cached ranking when a post is deleted, hidden or restored so that the
+// explore feed never serves a post that cannot be seen. The next request ranks the posts again.
+func (app *application) trendingChanged(r *http.Request) {
+	if !app.config.cache.enabled {
+		return
+	}
+
+	if err := app.cacheStore.Explore.Delete(r.Context()); err != nil {
+		app.logger.Error("failed to evict the trending posts from cache", "error", err)
+	}
+}
+
+// runExploreRefresh precomputes the trending posts until ctx is canceled. Every instance of the
+// API refreshes the same ranking, which is cheap enough not to coordinate them.
+func (app *application) runExploreRefresh(ctx context.Context) {
+	if !app.config.cache.enabled || app.config.explore.refresh <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(app.config.explore.refresh)
+	defer ticker.Stop()
+
+	for {
+		if _, err := app.refreshTrending(ctx); err != nil {
+			app.logger.Error("failed to refresh the trending posts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExplore(t *testing.T) {
+	app := newTestApp(t, config{
+		explore: exploreConfig{window: 7 * 24 * time.Hour, size: 500},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explore := func(query string) ([]int64, int) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/explore?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code != http.StatusOK {
+			return nil, rr.Code
+		}
+
+		var body struct {
+			Data []store.TrendingPost `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []int64{}
+		for _, post := range body.Data {
+			ids = append(ids, post.ID)
+		}
+		return ids, rr.Code
+	}
+
+	for query, want := range map[string][]int64{
+		"":                        {1, 2, 3},
+		"tags=rust":               {2, 3},
+		"tags=go,rust":            {2},
+		"tags=java":               {},
+		"search=CRABS":            {1, 2, 3},
+		"search=ferris":           {},
+		"tags=rust&limit=1":       {2},
+		"tags=rust&offset=1":      {3},
+		"limit=2&offset=2":        {3},
+		"search=gophers&offset=5": {},
+	} {
+		ids, code := explore(query)
+		checkResponseCode(t, http.StatusOK, code)
+		if !slices.Equal(ids, want) {
+			t.Errorf("%q: expected posts %v but got %v", query, want, ids)
+		}
+	}
+
+	for _, query := range []string{"limit=0", "limit=21", "offset=-1", "tags=a,b,c,d,e,f"} {
+		if _, code := explore(query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected response code %d but got %d", query, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestExploreCache(t *testing.T) {
+	app := newTestApp(t, config{
+		cache:   cacheConfig{enabled: true},
+		explore: exploreConfig{window: 7 * 24 * time.Hour, size: 500},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := app.cacheStore.Users.(*cache.MockUsersCacheStorage)
+	users.On("Get", int64(1)).Return(nil, nil)
+	users.On("Set", mock.Anything).Return(nil)
+
+	explore := app.cacheStore.Explore.(*cache.MockExploreCacheStorage)
+
+	request := func(method, path string) int {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux).Code
+	}
+
+	t.Run("should cache the ranking for a while without refreshes", func(t *testing.T) {
+		explore.On("Get").Return(nil, nil).Once()
+		explore.On("Set", mock.Anything, minExploreExpiry).Return(nil).Once()
+
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/explore"))
+		explore.AssertExpectations(t)
+	})
+
+	t.Run("should evict the ranking when a post is deleted", func(t *testing.T) {
+		explore.On("Delete").Return(nil).Once()
+
+		checkResponseCode(t, http.StatusNoContent, request(http.MethodDelete, "/v1/posts/1"))
+		explore.AssertExpectations(t)
+	})
+}
@@ -14,10 +14,11 @@ import (
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
-//	@Param			since	query		string	false	"Since"
-//	@Param			until	query		string	false	"Until"
+//	@Param			since	query		string	false	"Oldest creation time, RFC3339 or 2006-01-02 15:04:05 in UTC"
+//	@Param			until	query		string	false	"Newest creation time, RFC3339 or 2006-01-02 15:04:05 in UTC"
 //	@Param			limit	query		int		false	"Limit"
 //	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
 //	@Param			sort	query		string	false	"Sort"
 //	@Param			tags	query		string	false	"Tags"
 //	@Param			search	query		string	false	"Search"
@@ -29,6 +30,7 @@ import (
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Pagination -- sliding window approach: feed?limit=20&offset=0
+	// Keyset approach: feed?limit=20&cursor=<next_cursor from the previous page>
 	fq := store.PaginatedFeedQuery{
 		Limit:  20,
 		Offset: 0,
@@ -59,7 +61,13 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
+	nextCursor, err := store.NextFeedCursor(feed, fq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, feed, nextCursor); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
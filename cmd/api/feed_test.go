@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFeedTimeRange(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		since, until string
+		want         int
+	}{
+		{"", "", http.StatusOK},
+		{"2025-01-01T00:00:00Z", "", http.StatusOK},
+		{"2025-01-01T00:00:00+02:00", "2025-02-01T00:00:00-05:00", http.StatusOK},
+		{"2025-01-01 00:00:00", "2025-02-01 23:59:59", http.StatusOK},
+		{"", "2025-02-01 23:59:59", http.StatusOK},
+		{"2025-01-01", "", http.StatusBadRequest},
+		{"yesterday", "", http.StatusBadRequest},
+		{"", "2025-02-30 00:00:00", http.StatusBadRequest},
+		{"", "1738368000", http.StatusBadRequest},
+	} {
+		qs := url.Values{}
+		if tc.since != "" {
+			qs.Set("since", tc.since)
+		}
+		if tc.until != "" {
+			qs.Set("until", tc.until)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed?"+qs.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code != tc.want {
+			t.Errorf(
+				"since %q until %q: expected response code %d but got %d",
+				tc.since, tc.until, tc.want, rr.Code,
+			)
+		}
+	}
+}
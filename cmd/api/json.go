@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,6 +13,11 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	// Post tags, see store.IsValidTag
+	_ = Validate.RegisterValidation("tag", func(fl validator.FieldLevel) bool { // nolint:errcheck
+		return store.IsValidTag(fl.Field().String())
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -43,3 +50,25 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 	}
 	return writeJSON(w, status, &envelope{Data: data})
 }
+
+// paginatedJSONResponse writes a page of results along with the opaque cursor of the next page.
+// The cursor is also advertised in a Link header (RFC 8288) so clients can follow it directly.
+func (app *application) paginatedJSONResponse(
+	w http.ResponseWriter, r *http.Request, status int, data any, nextCursor string,
+) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+	}
+
+	if nextCursor != "" {
+		qs := r.URL.Query()
+		qs.Del("offset")
+		qs.Set("cursor", nextCursor)
+		w.Header().Set(
+			"Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, app.config.apiURL, r.URL.Path, qs.Encode()),
+		)
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: nextCursor})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/atomicmeganerd/gopher-social/internal/i18n"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// locale negotiates the locale of a response. An Accept-Language header asking for a supported
+// locale wins, then comes the locale the authenticated user prefers, then the default locale.
+func (app *application) locale(r *http.Request) string {
+	if locale, ok := app.i18n.Match(r.Header.Get("Accept-Language")); ok {
+		return locale
+	}
+
+	if user := getUserFromContext(r); user != nil && app.i18n.Supported(user.Locale) {
+		return user.Locale
+	}
+
+	return i18n.DefaultLocale
+}
+
+// translate translates a message in the locale of the response
+func (app *application) translate(
+	w http.ResponseWriter, r *http.Request, key string, args ...any,
+) string {
+	locale := app.locale(r)
+	w.Header().Set("Content-Language", locale)
+	return app.i18n.T(locale, key, args...)
+}
+
+type UpdateLocalePayload struct {
+	Locale string `json:"locale" validate:"required,max=10"`
+}
+
+// updateLocaleHandler godoc
+//
+//	@Summary		Updates the preferred locale
+//	@Description	Sets the locale of the authenticated user, used for emails and for messages
+//	@Description	when requests have no Accept-Language header
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateLocalePayload	true	"Locale"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/locale [put]
+func (app *application) updateLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateLocalePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if !app.i18n.Supported(payload.Locale) {
+		app.badRequestError(w, r, errUnsupportedLocale)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if err := app.dbStore.Users.UpdateLocale(r.Context(), user.ID, payload.Locale); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// The cached user must not keep serving the previous locale
+	if app.config.cache.enabled {
+		if err := app.cacheStore.Users.Delete(r.Context(), user.ID); err != nil {
+			app.logger.Error("failed to evict user from cache", "userID", user.ID, "error", err)
+		}
+	}
+
+	user.Locale = payload.Locale
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
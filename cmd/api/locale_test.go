@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	for header, want := range map[string]string{
+		"":                 `"unauthorized"`,
+		"fr-CA,fr;q=0.9":   `"non autorisé"`,
+		"de-DE,de;q=0.9":   `"unauthorized"`,
+		"en-US,en;q=0.9":   `"unauthorized"`,
+		"de-DE,fr;q=0.5":   `"non autorisé"`,
+		"fr;q=0.1,en;q=.9": `"unauthorized"`,
+	} {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Language", header)
+
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("%q: expected %s but got %s", header, want, rr.Body.String())
+		}
+	}
+}
+
+func TestUpdateLocale(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for locale, want := range map[string]int{
+		"fr": http.StatusOK,
+		"en": http.StatusOK,
+		"de": http.StatusBadRequest,
+		"":   http.StatusBadRequest,
+	} {
+		req, err := http.NewRequest(
+			http.MethodPut,
+			"/v1/users/locale",
+			strings.NewReader(fmt.Sprintf(`{"locale": %q}`, locale)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, want, rr.Code)
+	}
+}
+
+func TestUpdateLocaleEvictsCachedUser(t *testing.T) {
+	app := newTestApp(t, config{cache: cacheConfig{enabled: true}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockCacheStore := app.cacheStore.Users.(*cache.MockUsersCacheStorage)
+	mockCacheStore.On("Get", int64(1)).Return(nil, nil)
+	mockCacheStore.On("Set", mock.Anything).Return(nil)
+	mockCacheStore.On("Delete", int64(1)).Return(nil).Once()
+
+	req, err := http.NewRequest(
+		http.MethodPut, "/v1/users/locale", strings.NewReader(`{"locale": "fr"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+	rr := execMockRequests(req, mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+	mockCacheStore.AssertExpectations(t)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"expvar"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/atomicmeganerd/gopher-social/internal/auth"
 	"github.com/atomicmeganerd/gopher-social/internal/db"
+	"github.com/atomicmeganerd/gopher-social/internal/i18n"
 	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/outbox"
 	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
@@ -64,25 +67,64 @@ func main() {
 	}
 	cacheStore := cache.NewCacheStorage(rds)
 
-	mailer := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
+	catalog, err := i18n.NewCatalog(i18n.FS)
+	if err != nil {
+		log.Fatalf("failed to load the message catalogs: %v", err)
+	}
+
+	// Broken email templates fail at startup rather than when an email is sent
+	templates, err := mailer.NewTemplates(mailer.FS)
+	if err != nil {
+		log.Fatalf("failed to load the email templates: %v", err)
+	}
+	mailer, err := newMailer(cfg.mail, templates)
+	if err != nil {
+		log.Fatalf("failed to configure the mailer: %v", err)
+	}
+	logger.Info("mailer configured", "backend", cfg.mail.backend)
 	jwtAuthenticator := auth.NewJWTAuthenticator(
 		cfg.auth.jwtToken.secret,
 		cfg.auth.jwtToken.tokenHost,
 		cfg.auth.jwtToken.tokenHost,
+		dbStore.Sessions,
 	)
+	if cfg.auth.jwtToken.keysDir != "" {
+		keys, err := auth.LoadKeySet(cfg.auth.jwtToken.keysDir, cfg.auth.jwtToken.activeKeyID)
+		if err != nil {
+			log.Fatalf("failed to load the jwt signing keys: %v", err)
+		}
+		jwtAuthenticator = auth.NewAsymmetricJWTAuthenticator(
+			keys,
+			cfg.auth.jwtToken.tokenHost,
+			cfg.auth.jwtToken.tokenHost,
+			dbStore.Sessions,
+		)
+		logger.Info("jwt signing keys loaded", "active", cfg.auth.jwtToken.activeKeyID)
+	}
 
-	rateLimiter := ratelimiter.NewFixedWindowLimiter(
-		cfg.rateLimiter.RequestsPerTimeFrame,
-		cfg.rateLimiter.TimeFrame,
+	// The redis limiters need redis even when the cache is disabled
+	if cfg.rateLimiter.Backend == ratelimiter.BackendRedis && rds == nil {
+		rds = cache.NewRedisClient(cfg.cache.addr, cfg.cache.password, cfg.cache.db)
+	}
+	rateLimiter, err := ratelimiter.NewPolicies(cfg.rateLimiter, rds)
+	if err != nil {
+		log.Fatalf("failed to configure the rate limiter: %v", err)
+	}
+	logger.Info(
+		"rate limiter configured",
+		"backend", cfg.rateLimiter.Backend,
+		"algorithm", cfg.rateLimiter.Algorithm,
 	)
 
 	app := &application{
 		config:        cfg,
 		cacheStore:    cacheStore,
 		dbStore:       dbStore,
-		mailer:        mailer,
+		outbox:        outbox.NewWorker(cfg.outbox, dbStore.Outbox, mailer, logger),
 		logger:        logger,
 		authenticator: jwtAuthenticator,
+		signingKeys:   jwtAuthenticator.Keys(),
+		i18n:          catalog,
 		rateLimiter:   rateLimiter,
 	}
 
@@ -112,3 +154,24 @@ func main() {
 	mux := app.mount()
 	log.Fatal(app.run(mux))
 }
+
+// newMailer creates the mailer of the configured backend
+func newMailer(cfg mailConfig, templates *mailer.Templates) (mailer.Client, error) {
+	switch cfg.backend {
+	case mailer.BackendSendGrid:
+		return mailer.NewSendgrid(cfg.sendGrid.apiKey, cfg.fromEmail, templates), nil
+	case mailer.BackendSMTP:
+		return mailer.NewSMTP(
+			cfg.smtp.host,
+			cfg.smtp.port,
+			cfg.smtp.username,
+			cfg.smtp.password,
+			cfg.fromEmail,
+			templates,
+		), nil
+	case mailer.BackendFile:
+		return mailer.NewFile(cfg.dir, cfg.fromEmail, templates), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", cfg.backend)
+	}
+}
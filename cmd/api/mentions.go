@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// How much of the content is quoted in the mention emails
+const mentionExcerptLength = 200
+
+// resolveEntities parses the mentions and hashtags of the content and resolves the mentions to
+// the active users, the mentions of unknown users are dropped. It also returns the mentioned
+// users.
+func (app *application) resolveEntities(
+	ctx context.Context, content string,
+) ([]store.Entity, []store.User, error) {
+	entities := store.ParseEntities(content)
+
+	usernames := store.MentionedUsernames(entities)
+	if len(usernames) == 0 {
+		return entities, nil, nil
+	}
+
+	users, err := app.dbStore.Users.GetByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userIDs := map[string]int64{}
+	for _, user := range users {
+		userIDs[user.Username] = user.ID
+	}
+
+	resolved := []store.Entity{}
+	for _, entity := range entities {
+		if entity.Type == store.EntityMention {
+			userID, ok := userIDs[strings.TrimPrefix(entity.Text, "@")]
+			if !ok {
+				continue
+			}
+			entity.UserID = userID
+		}
+		resolved = append(resolved, entity)
+	}
+
+	return resolved, users, nil
+}
+
+// mergeHashtags adds the hashtags of the content to the tags of a post while there is room, the
+// tags given explicitly come first.
+func mergeHashtags(tags []string, entities []store.Entity) []string {
+	merged := store.NormalizeTags(append(slices.Clone(tags), store.Hashtags(entities)...))
+	return merged[:min(len(merged), maxPostTags)]
+}
+
+// mentionEmail builds the emails notifying the users mentioned in the content by the author of
+// the request. The store calls it for the mentions it records, in the transaction that saves the
+// content, so a user is emailed when first mentioned and not on every edit. The author is never
+// notified.
+func (app *application) mentionEmail(
+	r *http.Request, mentioned []store.User, content string,
+) store.MentionEmailFunc {
+	author := getUserFromContext(r)
+	isProdEnv := app.config.env == "production"
+
+	excerpt := []rune(content)
+	if len(excerpt) > mentionExcerptLength {
+		excerpt = append(excerpt[:mentionExcerptLength], '…')
+	}
+
+	users := map[int64]store.User{}
+	for _, user := range mentioned {
+		users[user.ID] = user
+	}
+
+	return func(postID, userID int64) (*store.OutboxEmail, error) {
+		user, ok := users[userID]
+		if !ok || user.ID == author.ID {
+			return nil, nil
+		}
+
+		vars := struct {
+			Username string
+			Author   string
+			Excerpt  string
+			URL      string
+		}{
+			Username: user.Username,
+			Author:   author.Username,
+			Excerpt:  string(excerpt),
+			URL:      fmt.Sprintf("%s/posts/%d", app.config.frontendURL, postID),
+		}
+
+		return store.NewOutboxEmail(
+			mailer.MentionTemplate, user.Locale, user.Username, user.Email, vars, !isProdEnv,
+		)
+	}
+}
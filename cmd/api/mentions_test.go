@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestMentions(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox := app.dbStore.Outbox.(*store.MockOutboxStore)
+
+	request := func(method, path, body string, data any) int {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code < http.StatusBadRequest {
+			envelope := struct {
+				Data any `json:"data"`
+			}{Data: data}
+			if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code
+	}
+
+	t.Run("should resolve the mentions and merge the hashtags of a post", func(t *testing.T) {
+		outbox.Enqueued = nil
+
+		var post store.Post
+		checkResponseCode(t, http.StatusCreated, request(http.MethodPost, "/v1/posts", `{
+			"title": "Gophers",
+			"content": "Hey @gopher and @nobody, #Rust or #go?",
+			"tags": ["go", "a", "b", "c"]
+		}`, &post))
+
+		if !slices.Equal(post.Tags, []string{"go", "a", "b", "c", "rust"}) {
+			t.Errorf("expected the hashtags in the tags but got %v", post.Tags)
+		}
+
+		mentions := []int64{}
+		for _, entity := range post.Entities {
+			if entity.Type == store.EntityMention {
+				mentions = append(mentions, entity.UserID)
+			}
+		}
+		if !slices.Equal(mentions, []int64{2}) {
+			t.Errorf("expected a mention of the user 2 but got %v", mentions)
+		}
+
+		if len(outbox.Enqueued) != 1 || outbox.Enqueued[0].Username != "gopher" ||
+			outbox.Enqueued[0].Template != mailer.MentionTemplate {
+			t.Errorf("expected a mention email to gopher but got %+v", outbox.Enqueued)
+		}
+	})
+
+	t.Run("should notify the users mentioned in comments", func(t *testing.T) {
+		outbox.Enqueued = nil
+
+		var comment store.Comment
+		checkResponseCode(t, http.StatusCreated, request(
+			http.MethodPost, "/v1/posts/1/comments",
+			`{"content": "@ferris @gopher @ferris"}`, &comment,
+		))
+		if len(comment.Entities) != 3 {
+			t.Errorf("expected 3 entities but got %+v", comment.Entities)
+		}
+		if len(outbox.Enqueued) != 2 {
+			t.Errorf("expected 2 mention emails but got %d", len(outbox.Enqueued))
+		}
+
+		outbox.Enqueued = nil
+		checkResponseCode(t, http.StatusOK, request(
+			http.MethodPatch, "/v1/posts/1/comments/1", `{"content": "Thanks @ferris"}`, &comment,
+		))
+		if len(outbox.Enqueued) != 1 || outbox.Enqueued[0].Username != "ferris" {
+			t.Errorf("expected a mention email to ferris but got %+v", outbox.Enqueued)
+		}
+	})
+}
@@ -5,25 +5,75 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
+// Rate limit policies of the route groups, see config.go for their budgets
+const (
+	rateLimitPolicyOps   = "ops"
+	rateLimitPolicyAuth  = "auth"
+	rateLimitPolicyPosts = "posts"
+	// Counted per email address rather than per client, see resendActivationHandler
+	rateLimitPolicyResend = "resend"
+)
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.rateLimiter.Enabled {
-			if allow, retryAfter := app.rateLimiter.Allow(r.RemoteAddr); !allow {
-				app.rateLimitExceeededError(w, r, retryAfter.String())
-				return
+// RateLimiterMiddleware limits the requests of a route group by the given policy. Requests are
+// counted per authenticated user when AuthTokenMiddleware ran before this middleware, so users
+// behind a shared NAT do not share a budget, and per IP address otherwise.
+func (app *application) RateLimiterMiddleware(policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if app.config.rateLimiter.Enabled {
+				key, tier := app.rateLimitClient(r)
+				decision := app.rateLimiter.Allow(policy, tier, key)
+
+				// Let clients pace themselves before they hit the limit
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+				w.Header().Set("X-RateLimit-Reset", durationToSeconds(decision.ResetAfter))
+
+				if !decision.Allowed {
+					app.rateLimitExceeededError(w, r, durationToSeconds(decision.RetryAfter))
+					return
+				}
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClient identifies the client of a request and the tier of its budget
+func (app *application) rateLimitClient(r *http.Request) (string, ratelimiter.Tier) {
+	user := getUserFromContext(r)
+	if user == nil {
+		// The port changes with every connection of the client, RealIP sets no port
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
 		}
-		next.ServeHTTP(w, r)
-	})
+		return "ip-" + host, ratelimiter.TierUser
+	}
+
+	key := fmt.Sprintf("user-%d", user.ID)
+	if user.Role.Level >= app.config.rateLimiter.AdminLevel {
+		return key, ratelimiter.TierAdmin
+	}
+	return key, ratelimiter.TierUser
+}
+
+// durationToSeconds formats a duration as whole seconds rounded up, as used by the Retry-After
+// and X-RateLimit-Reset headers.
+func durationToSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
 }
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
@@ -62,9 +112,11 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		user, err := app.getUser(ctx, userID)
 		if err != nil {
 			app.unauthorizedError(w, r, err)
+			return
 		}
 
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, claimsCtx, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -116,11 +168,28 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 }
 
 func (app *application) checkPostOwnership(role string, next http.HandlerFunc) http.HandlerFunc {
+	return app.checkOwnership(role, func(r *http.Request) int64 {
+		return getPostFromContext(r).UserID
+	}, next)
+}
+
+func (app *application) checkCommentOwnership(
+	role string, next http.HandlerFunc,
+) http.HandlerFunc {
+	return app.checkOwnership(role, func(r *http.Request) int64 {
+		return getCommentFromContext(r).UserID
+	}, next)
+}
+
+// checkOwnership lets the owner of the content in the request context through, as well as the
+// users with at least the level of the given role.
+func (app *application) checkOwnership(
+	role string, ownerID func(*http.Request) int64, next http.HandlerFunc,
+) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := getUserFromContext(r)
-		post := getPostFromContext(r)
 
-		if post.UserID == user.ID {
+		if ownerID(r) == user.ID {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -140,6 +209,29 @@ func (app *application) checkPostOwnership(role string, next http.HandlerFunc) h
 	})
 }
 
+// requireRole only lets users with at least the level of the given role through, it must come
+// after AuthTokenMiddleware.
+func (app *application) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			allowed, err := app.checkRolePrecedence(r.Context(), user, role)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenError(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) checkRolePrecedence(
 	ctx context.Context, user *store.User, roleName string,
 ) (bool, error) {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/google/uuid"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=64"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// forgotPasswordHandler godoc
+//
+//	@Summary		Requests a password reset
+//	@Description	Emails a one time password reset link to the user. The response is the same
+//	@Description	whether the email belongs to an account or not.
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	ForgotPasswordPayload	true	"Account email"
+//	@Success		202		"Reset requested"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/password/forgot [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user, err := app.dbStore.Users.GetByEmail(r.Context(), payload.Email)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			// NOTE: do not reveal whether the email belongs to an account
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	plainToken := uuid.New().String()
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password/%s", app.config.frontendURL, plainToken),
+		ExpiresIn: app.config.mail.passwordResetExp.String(),
+	}
+
+	email, err := store.NewOutboxEmail(
+		mailer.PasswordResetTemplate, user.Locale, user.Username, user.Email, vars, !isProdEnv,
+	)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.dbStore.Users.CreatePasswordReset(
+		r.Context(), user.ID, hashToken(plainToken), app.config.mail.passwordResetExp, email,
+	); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// resetPasswordHandler godoc
+//
+//	@Summary		Resets a password
+//	@Description	Sets a new password with the token of a password reset email and signs the
+//	@Description	user out of every session
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body	ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		204		"Password reset"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/password/reset [post]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.dbStore.Users.ResetPassword(
+		r.Context(), payload.Token, payload.Password,
+	); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -14,15 +14,23 @@ const postCtx postkey = "post"
 
 type postkey string
 
+// maxPostTags is the number of tags a post can have, hashtags included
+const maxPostTags = 5
+
+// Tags are case folded and their # prefix is dropped, they can have up to 30 letters, digits,
+// underscores and hyphens. The hashtags of the content are added to them up to the limit.
 type CreatePostPayload struct {
 	Title   string   `json:"title" validate:"required,max=100"`
 	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Tags    []string `json:"tags" validate:"max=5,dive,tag"`
 }
 
+// UpdatePostPayload replaces the tags of the post when they are given, an empty list removes
+// them.
 type UpdatePostPayload struct {
-	Title   *string `json:"title,omitempty" validate:"omitempty,max=100"`
-	Content *string `json:"content,omitempty" validate:"omitempty,max=1000"`
+	Title   *string   `json:"title,omitempty" validate:"omitempty,max=100"`
+	Content *string   `json:"content,omitempty" validate:"omitempty,max=1000"`
+	Tags    *[]string `json:"tags,omitempty" validate:"omitempty,max=5,dive,tag"`
 }
 
 // CreatePost godoc
@@ -54,14 +62,22 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	entities, mentioned, err := app.resolveEntities(ctx, payload.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	post := &store.Post{
-		UserID:  user.ID,
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
+		UserID:   user.ID,
+		Title:    payload.Title,
+		Content:  payload.Content,
+		Tags:     mergeHashtags(payload.Tags, entities),
+		Entities: entities,
 	}
 
-	if err := app.dbStore.Posts.Create(ctx, post); err != nil {
+	mentionEmail := app.mentionEmail(r, mentioned, post.Content)
+	if err := app.dbStore.Posts.Create(ctx, post, mentionEmail); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -78,16 +94,27 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	store.Post
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			depth	query		int	false	"Levels of comment replies to return"
+//	@Param			limit	query		int	false	"Comments per level"
+//	@Param			offset	query		int	false	"Offset of the top level comments"
+//	@Success		200		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
+	user := getUserFromContext(r)
 
-	comments, err := app.dbStore.Comments.GetByPostID(r.Context(), post.ID)
+	cq, err := app.parseCommentQuery(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	comments, err := app.dbStore.Comments.GetByPostID(r.Context(), post.ID, cq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -95,6 +122,14 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
+	reactions, err := app.dbStore.Reactions.GetSummary(r.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Reactions = *reactions
+
 	if err = app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -116,7 +151,7 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
 
-	err := app.dbStore.Posts.Delete(r.Context(), post.ID)
+	err := app.dbStore.Posts.Delete(r.Context(), post.ID, getUserFromContext(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -127,6 +162,9 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.recordAudit(r, store.AuditPostDeleted, store.AuditTargetPost, post.ID, post, nil)
+	app.trendingChanged(r)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -149,6 +187,7 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post := getPostFromContext(r)
+	before := *post
 
 	var payload UpdatePostPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -169,7 +208,21 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		post.Content = *payload.Content
 	}
 
-	if err := app.dbStore.Posts.Update(r.Context(), post); err != nil {
+	if payload.Tags != nil {
+		post.Tags = *payload.Tags
+	}
+
+	entities, mentioned, err := app.resolveEntities(r.Context(), post.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Entities = entities
+	post.Tags = mergeHashtags(post.Tags, entities)
+
+	mentionEmail := app.mentionEmail(r, mentioned, post.Content)
+	if err := app.dbStore.Posts.Update(r.Context(), post, mentionEmail); err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notFoundError(w, r, err)
@@ -179,6 +232,8 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.recordAudit(r, store.AuditPostUpdated, store.AuditTargetPost, post.ID, before, post)
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 	}
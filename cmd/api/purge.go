@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runPurge removes for good the posts and comments deleted longer than the retention period
+// ago, until ctx is canceled. Purging is idempotent so every instance of the API can run it.
+func (app *application) runPurge(ctx context.Context) {
+	if app.config.purge.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(app.config.purge.interval)
+	defer ticker.Stop()
+
+	for {
+		app.purgeDeleted(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) purgeDeleted(ctx context.Context) {
+	deletedBefore := time.Now().Add(-app.config.purge.retention)
+
+	posts, err := app.dbStore.Posts.Purge(ctx, deletedBefore)
+	if err != nil {
+		app.logger.Error("failed to purge deleted posts", "error", err)
+		return
+	}
+
+	comments, err := app.dbStore.Comments.Purge(ctx, deletedBefore)
+	if err != nil {
+		app.logger.Error("failed to purge deleted comments", "error", err)
+		return
+	}
+
+	if posts > 0 || comments > 0 {
+		app.logger.Info("purged deleted content", "posts", posts, "comments", comments)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// ReactToPost godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Sets the reaction of the user to a post, replacing any previous reaction
+//	@Tags			reactions
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			kind	path		string	true	"Reaction kind"	Enums(like, love, haha, wow, sad, angry)
+//	@Success		200		{object}	store.ReactionSummary
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions/{kind} [put]
+func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	reaction, err := reactionFromRequest(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.dbStore.Reactions.Set(ctx, reaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	summary, err := app.dbStore.Reactions.GetSummary(ctx, reaction.PostID, reaction.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, summary); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RemovePostReaction godoc
+//
+//	@Summary		Removes a reaction from a post
+//	@Description	Removes the reaction of the user from a post
+//	@Tags			reactions
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			kind	path		string	true	"Reaction kind"	Enums(like, love, haha, wow, sad, angry)
+//	@Success		204		{object}	string
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions/{kind} [delete]
+func (app *application) removePostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	reaction, err := reactionFromRequest(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.dbStore.Reactions.Delete(r.Context(), reaction); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func reactionFromRequest(r *http.Request) (*store.Reaction, error) {
+	kind := store.ReactionKind(chi.URLParam(r, "kind"))
+	if !kind.Valid() {
+		return nil, store.ErrInvalidReaction
+	}
+
+	return &store.Reaction{
+		PostID: getPostFromContext(r).ID,
+		UserID: getUserFromContext(r).ID,
+		Kind:   kind,
+	}, nil
+}
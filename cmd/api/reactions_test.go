@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestPostReactions(t *testing.T) {
+	app := newTestApp(t, config{
+		comments: commentsConfig{maxDepth: 5, pageSize: 20},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path string, data any) int {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if data != nil && rr.Code == http.StatusOK {
+			envelope := struct {
+				Data any `json:"data"`
+			}{Data: data}
+			if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code
+	}
+
+	t.Run("should reject unknown reaction kinds", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusBadRequest, request(http.MethodPut, "/v1/posts/1/reactions/meh", nil),
+		)
+		checkResponseCode(
+			t, http.StatusBadRequest, request(http.MethodDelete, "/v1/posts/1/reactions/meh", nil),
+		)
+	})
+
+	t.Run("should not react to unknown posts", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusNotFound, request(http.MethodPut, "/v1/posts/2/reactions/like", nil),
+		)
+	})
+
+	t.Run("should replace the reaction of the user", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodPut, "/v1/posts/1/reactions/like", nil),
+		)
+
+		var summary store.ReactionSummary
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodPut, "/v1/posts/1/reactions/love", &summary),
+		)
+
+		if summary.Total != 1 || summary.Counts[store.ReactionLove] != 1 ||
+			summary.Counts[store.ReactionLike] != 0 {
+			t.Errorf("expected a single love reaction but got %+v", summary)
+		}
+		if summary.ViewerReaction == nil || *summary.ViewerReaction != store.ReactionLove {
+			t.Errorf("expected the viewer reaction to be love but got %v", summary.ViewerReaction)
+		}
+	})
+
+	t.Run("should count the reactions in the post", func(t *testing.T) {
+		var post store.Post
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/posts/1", &post))
+
+		if post.Reactions.Total != 1 || post.Reactions.Counts[store.ReactionLove] != 1 {
+			t.Errorf("expected a love reaction on the post but got %+v", post.Reactions)
+		}
+		if post.Reactions.ViewerReaction == nil ||
+			*post.Reactions.ViewerReaction != store.ReactionLove {
+			t.Errorf(
+				"expected the viewer reaction to be love but got %v", post.Reactions.ViewerReaction,
+			)
+		}
+	})
+
+	t.Run("should remove the reaction of the user", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusNotFound, request(http.MethodDelete, "/v1/posts/1/reactions/like", nil),
+		)
+		checkResponseCode(
+			t, http.StatusNoContent, request(http.MethodDelete, "/v1/posts/1/reactions/love", nil),
+		)
+
+		var post store.Post
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/posts/1", &post))
+		if post.Reactions.Total != 0 || post.Reactions.ViewerReaction != nil {
+			t.Errorf("expected no reactions on the post but got %+v", post.Reactions)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type ReportPayload struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence nudity misinformation other"`
+	Details string `json:"details" validate:"max=500"`
+}
+
+// ReportPost godoc
+//
+//	@Summary		Reports a post
+//	@Description	Reports a post to the moderators, a post can be reported once per user
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int				true	"Post ID"
+//	@Param			payload	body		ReportPayload	true	"Report"
+//	@Success		201		{object}	store.Report
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/report [post]
+func (app *application) reportPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+	app.createReport(w, r, store.ReportTargetPost, post.ID)
+}
+
+// ReportComment godoc
+//
+//	@Summary		Reports a comment
+//	@Description	Reports a comment to the moderators, a comment can be reported once per user
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int				true	"Post ID"
+//	@Param			commentID	path		int				true	"Comment ID"
+//	@Param			payload		body		ReportPayload	true	"Report"
+//	@Success		201			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/report [post]
+func (app *application) reportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromContext(r)
+	app.createReport(w, r, store.ReportTargetComment, comment.ID)
+}
+
+func (app *application) createReport(
+	w http.ResponseWriter, r *http.Request, targetType string, targetID int64,
+) {
+	var payload ReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	report := &store.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReporterID: getUserFromContext(r).ID,
+		Reason:     payload.Reason,
+		Details:    payload.Details,
+	}
+
+	if err := app.dbStore.Reports.Create(r.Context(), report); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ListReports godoc
+//
+//	@Summary		Lists the moderation queue
+//	@Description	Lists the reports, oldest first, open reports unless another status is given
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			status		query		string	false	"open, claimed or resolved"
+//	@Param			target_type	query		string	false	"post or comment"
+//	@Param			reason		query		string	false	"Reason code"
+//	@Param			claimed_by	query		int		false	"Moderator ID"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			cursor		query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200			{object}	[]store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports [get]
+func (app *application) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	rq := store.PaginatedReportQuery{
+		Limit:  20,
+		Status: store.ReportStatusOpen,
+	}
+
+	rq, err := rq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(rq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	reports, err := app.dbStore.Reports.List(r.Context(), rq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, err := store.NextReportCursor(reports, rq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, reports, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ClaimReport godoc
+//
+//	@Summary		Claims a report
+//	@Description	Assigns an open report to the moderator, who is then the only one able to resolve it
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			reportID	path		int	true	"Report ID"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Claimed by another moderator or resolved"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/claim [post]
+func (app *application) claimReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	report, err := app.dbStore.Reports.Claim(r.Context(), reportID, getUserFromContext(r).ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		case store.ErrConflict:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type ResolveReportPayload struct {
+	Resolution string `json:"resolution" validate:"required,oneof=dismiss hide delete"`
+	Note       string `json:"note" validate:"max=500"`
+}
+
+// ResolveReport godoc
+//
+//	@Summary		Resolves a report
+//	@Description	Dismisses the report, hides or deletes the reported content. Deleting requires
+//	@Description	the admin role. The other pending reports of the content are resolved too.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		int						true	"Report ID"
+//	@Param			payload		body		ResolveReportPayload	true	"Resolution"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Not claimed by the moderator"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/resolve [post]
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	// Moderators can hide content, only admins can delete it like with checkPostOwnership
+	if payload.Resolution == store.ResolutionDelete {
+		allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenError(w, r)
+			return
+		}
+	}
+
+	report, err := app.dbStore.Reports.GetByID(ctx, reportID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	content, err := app.reportedContent(r, report)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report, err = app.dbStore.Reports.Resolve(
+		ctx, reportID, user.ID, payload.Resolution, payload.Note,
+	)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		case store.ErrConflict:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if action, ok := resolutionAuditActions[report.TargetType][payload.Resolution]; ok {
+		app.recordAudit(r, action, report.TargetType, report.TargetID, content, nil)
+		if report.TargetType == store.ReportTargetPost {
+			app.trendingChanged(r)
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// resolutionAuditActions are the audited resolutions, dismissing a report changes nothing
+var resolutionAuditActions = map[string]map[string]string{
+	store.ReportTargetPost: {
+		store.ResolutionHide:   store.AuditPostHidden,
+		store.ResolutionDelete: store.AuditPostDeleted,
+	},
+	store.ReportTargetComment: {
+		store.ResolutionHide:   store.AuditCommentHidden,
+		store.ResolutionDelete: store.AuditCommentDeleted,
+	},
+}
+
+// reportedContent returns the reported post or comment for the audit trail, or nil if it was
+// already hidden or deleted.
+func (app *application) reportedContent(r *http.Request, report *store.Report) (any, error) {
+	var content any
+	var err error
+	switch report.TargetType {
+	case store.ReportTargetPost:
+		content, err = app.dbStore.Posts.GetByID(r.Context(), report.TargetID)
+	case store.ReportTargetComment:
+		content, err = app.dbStore.Comments.GetByID(r.Context(), report.TargetID)
+	}
+
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+
+	return content, err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestReportContent(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/v1/posts/1/report", "/v1/posts/1/comments/1/report"} {
+		req, err := http.NewRequest(
+			http.MethodPost, path, strings.NewReader(`{"reason": "spam", "details": "Ads"}`),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var body struct {
+			Data store.Report `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.ID == 0 {
+			t.Errorf("%s: expected the ID of the new report but got %+v", path, body.Data)
+		}
+	}
+}
+
+func TestModerationQueue(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path string, body io.Reader) int {
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux).Code
+	}
+
+	t.Run("should forbid users", func(t *testing.T) {
+		app.dbStore.Users = &adminUserStore{role: store.Role{ID: 1, Name: "user", Level: 1}}
+
+		checkResponseCode(
+			t, http.StatusForbidden, request(http.MethodGet, "/v1/moderation/reports", nil),
+		)
+	})
+
+	app.dbStore.Users = &adminUserStore{role: store.Role{ID: 2, Name: "moderator", Level: 2}}
+
+	t.Run("should list the queue", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/v1/moderation/reports":                        http.StatusOK,
+			"/v1/moderation/reports?status=claimed":         http.StatusOK,
+			"/v1/moderation/reports?target_type=comment":    http.StatusOK,
+			"/v1/moderation/reports?status=closed":          http.StatusBadRequest,
+			"/v1/moderation/reports?target_type=user":       http.StatusBadRequest,
+			"/v1/moderation/reports?claimed_by=a-moderator": http.StatusBadRequest,
+		} {
+			if code := request(http.MethodGet, path, nil); code != want {
+				t.Errorf("%s: expected response code %d but got %d", path, want, code)
+			}
+		}
+	})
+
+	t.Run("should claim reports", func(t *testing.T) {
+		checkResponseCode(
+			t, http.StatusOK, request(http.MethodPost, "/v1/moderation/reports/7/claim", nil),
+		)
+	})
+
+	t.Run("should only let admins delete content", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, request(
+			http.MethodPost,
+			"/v1/moderation/reports/7/resolve",
+			strings.NewReader(`{"resolution": "ban"}`),
+		))
+		checkResponseCode(t, http.StatusForbidden, request(
+			http.MethodPost,
+			"/v1/moderation/reports/7/resolve",
+			strings.NewReader(`{"resolution": "delete"}`),
+		))
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// Search godoc
+//
+//	@Summary		Searches posts, comments and users
+//	@Description	Searches the posts and comments by full text and the usernames by similarity.
+//	@Description	The results are ranked by relevance, with the matched words of the title and
+//	@Description	snippet wrapped in <mark> tags.
+//	@Tags			search
+//	@Produce		json
+//	@Param			q		query		string	true	"Words, quoted phrases, or, and -word to exclude it"
+//	@Param			types	query		string	false	"Comma separated post, comment and user, all by default"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.SearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.PaginatedSearchQuery{
+		Limit: 20,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	results, err := app.dbStore.Search.Search(r.Context(), sq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestSearch(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string) int {
+		req, err := http.NewRequest(http.MethodGet, "/v1/search?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+		return execMockRequests(req, mux).Code
+	}
+
+	for query, want := range map[string]int{
+		"q=gophers":                      http.StatusOK,
+		"q=%22go+gophers%22+-rust":       http.StatusOK,
+		"q=gophers&types=post,user":      http.StatusOK,
+		"q=gophers&limit=50&offset=100":  http.StatusOK,
+		"":                               http.StatusBadRequest,
+		"q=+++":                          http.StatusBadRequest,
+		"q=gophers&types=tag":            http.StatusBadRequest,
+		"q=gophers&limit=100":            http.StatusBadRequest,
+		"q=gophers&offset=-1":            http.StatusBadRequest,
+		"q=gophers&types=post,,comment":  http.StatusBadRequest,
+		"q=gophers&offset=a+lot":         http.StatusBadRequest,
+		"q=gophers&types=post,user,post": http.StatusOK,
+	} {
+		if code := search(query); code != want {
+			t.Errorf("%q: expected response code %d but got %d", query, want, code)
+		}
+	}
+
+	t.Run("should pass the type filters", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, search("q=gophers&types=comment"))
+
+		searchStore := app.dbStore.Search.(*store.MockSearchStore)
+		if !slices.Equal(searchStore.LastQuery.Types, []string{store.SearchTypeComment}) {
+			t.Errorf("expected the comment type but got %v", searchStore.LastQuery.Types)
+		}
+	})
+}
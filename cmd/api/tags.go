@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const tagCtx tagKey = "tag"
+
+type tagKey string
+
+var errInvalidTag = errors.New("invalid tag")
+
+// GetTrendingTags godoc
+//
+//	@Summary		Fetches the trending tags
+//	@Description	Fetches the tags of the most recent posts, the posts count is the number of
+//	@Description	recent posts with the tag.
+//	@Tags			tags
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Success		200		{object}	[]store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tq := store.TrendingTagQuery{
+		Limit: 10,
+	}
+
+	tq, err := tq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(tq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tags, err := app.dbStore.Tags.GetTrending(r.Context(), app.config.explore.window, tq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTag godoc
+//
+//	@Summary		Fetches a tag
+//	@Description	Fetches a tag by name, case insensitively
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		200	{object}	store.Tag
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag} [get]
+func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getTagFromContext(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTagPosts godoc
+//
+//	@Summary		Fetches the posts of a tag
+//	@Description	Fetches the posts with a tag, newest first
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tq := store.PaginatedTagPostsQuery{
+		Limit: 20,
+	}
+
+	tq, err := tq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(tq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tag := getTagFromContext(r)
+	user := getUserFromContext(r)
+
+	posts, err := app.dbStore.Tags.GetPosts(r.Context(), tag.ID, user.ID, tq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, err := store.NextFeedCursor(posts, tq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, posts, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// FollowTag godoc
+//
+//	@Summary		Follows a tag
+//	@Description	Follows a tag, its posts then appear in the feed of the user
+//	@Tags			tags
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{string}	string	"Tag followed"
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error	"Tag already followed"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/follow [put]
+func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromContext(r)
+	user := getUserFromContext(r)
+
+	if err := app.dbStore.Tags.Follow(r.Context(), user.ID, tag.ID); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnfollowTag godoc
+//
+//	@Summary		Unfollows a tag
+//	@Description	Unfollows a tag
+//	@Tags			tags
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{string}	string	"Tag unfollowed"
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/unfollow [put]
+func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromContext(r)
+	user := getUserFromContext(r)
+
+	if err := app.dbStore.Tags.Unfollow(r.Context(), user.ID, tag.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagContextMiddleware loads the tag of the path, whatever its case
+func (app *application) tagContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "tag")
+		if !store.IsValidTag(name) {
+			app.badRequestError(w, r, errInvalidTag)
+			return
+		}
+
+		ctx := r.Context()
+
+		tag, err := app.dbStore.Tags.GetByName(ctx, name)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, tagCtx, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getTagFromContext(r *http.Request) *store.Tag {
+	tag, _ := r.Context().Value(tagCtx).(*store.Tag)
+	return tag
+}
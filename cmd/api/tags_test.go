@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+func TestTags(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/v1/tags/trending", http.StatusOK},
+		{http.MethodGet, "/v1/tags/trending?limit=100", http.StatusBadRequest},
+		{http.MethodGet, "/v1/tags/go", http.StatusOK},
+		{http.MethodGet, "/v1/tags/GO", http.StatusOK},
+		{http.MethodGet, "/v1/tags/%23Go", http.StatusOK},
+		{http.MethodGet, "/v1/tags/java", http.StatusNotFound},
+		{http.MethodGet, "/v1/tags/go!", http.StatusBadRequest},
+		{http.MethodGet, "/v1/tags/go/posts", http.StatusOK},
+		{http.MethodGet, "/v1/tags/go/posts?limit=0", http.StatusBadRequest},
+		{http.MethodGet, "/v1/tags/go/posts?cursor=nope", http.StatusBadRequest},
+		{http.MethodPut, "/v1/tags/Rust/follow", http.StatusNoContent},
+		{http.MethodPut, "/v1/tags/rust/unfollow", http.StatusNoContent},
+		{http.MethodPut, "/v1/tags/java/follow", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		if code := execMockRequests(req, mux).Code; code != tc.want {
+			t.Errorf("%s %s: expected response code %d but got %d", tc.method, tc.path, tc.want, code)
+		}
+	}
+}
+
+func TestPostTags(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, body string) (*store.Post, int) {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+
+		rr := execMockRequests(req, mux)
+		if rr.Code >= http.StatusBadRequest {
+			return nil, rr.Code
+		}
+
+		var envelope struct {
+			Data store.Post `json:"data"`
+		}
+		data, _ := io.ReadAll(rr.Body)
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		return &envelope.Data, rr.Code
+	}
+
+	t.Run("should fold the tags", func(t *testing.T) {
+		post, code := request(http.MethodPost, "/v1/posts",
+			`{"title": "Gophers", "content": "Gophers", "tags": ["Go", "#go", "Go-Lang"]}`)
+		checkResponseCode(t, http.StatusCreated, code)
+		if !slices.Equal(post.Tags, []string{"go", "go-lang"}) {
+			t.Errorf("expected the folded tags but got %v", post.Tags)
+		}
+	})
+
+	t.Run("should limit the tags", func(t *testing.T) {
+		for _, tags := range []string{
+			`["a", "b", "c", "d", "e", "f"]`,
+			`[""]`,
+			`["two words"]`,
+			`["` + strings.Repeat("a", 31) + `"]`,
+		} {
+			_, code := request(http.MethodPost, "/v1/posts",
+				`{"title": "Gophers", "content": "Gophers", "tags": `+tags+`}`)
+			checkResponseCode(t, http.StatusBadRequest, code)
+
+			_, code = request(http.MethodPatch, "/v1/posts/1", `{"tags": `+tags+`}`)
+			checkResponseCode(t, http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("should update the tags", func(t *testing.T) {
+		post, code := request(http.MethodPatch, "/v1/posts/1", `{"tags": ["Rust"]}`)
+		checkResponseCode(t, http.StatusOK, code)
+		if !slices.Equal(post.Tags, []string{"rust"}) {
+			t.Errorf("expected the rust tag but got %v", post.Tags)
+		}
+
+		post, code = request(http.MethodPatch, "/v1/posts/1", `{"tags": []}`)
+		checkResponseCode(t, http.StatusOK, code)
+		if len(post.Tags) != 0 {
+			t.Errorf("expected no tags but got %v", post.Tags)
+		}
+	})
+}
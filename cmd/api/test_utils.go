@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/atomicmeganerd/gopher-social/internal/auth"
+	"github.com/atomicmeganerd/gopher-social/internal/i18n"
 	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
@@ -27,10 +28,18 @@ func newTestApp(t *testing.T, cfg config) *application {
 	mockCache := cache.NewMockStore()
 	mockAuth := &auth.TestAuthenticator{}
 
-	rateLimiter := ratelimiter.NewFixedWindowLimiter(
-		cfg.rateLimiter.RequestsPerTimeFrame,
-		cfg.rateLimiter.TimeFrame,
-	)
+	rateLimiterCfg := cfg.rateLimiter
+	rateLimiterCfg.Backend = ratelimiter.BackendMemory
+	rateLimiterCfg.Algorithm = ratelimiter.AlgorithmFixedWindow
+	rateLimiter, err := ratelimiter.NewPolicies(rateLimiterCfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := i18n.NewCatalog(i18n.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	return &application{
 		logger:        logger,
@@ -39,6 +48,7 @@ func newTestApp(t *testing.T, cfg config) *application {
 		authenticator: mockAuth,
 		config:        cfg,
 		rateLimiter:   rateLimiter,
+		i18n:          catalog,
 	}
 }
 
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 const userCtx userKey = "user"
@@ -45,6 +49,15 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// NOTE: The counts change too often to be cached with the user so always load them
+	user.FollowersCount, user.FollowingCount, err = app.dbStore.Followers.GetCounts(
+		r.Context(), user.ID,
+	)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -119,6 +132,99 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// GetFollowers godoc
+//
+//	@Summary		Lists the followers of a user
+//	@Description	Lists the users following a user, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200		{object}	[]store.FollowEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/followers [get]
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.dbStore.Followers.GetFollowers)
+}
+
+// GetFollowing godoc
+//
+//	@Summary		Lists the users a user follows
+//	@Description	Lists the users followed by a user, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page"
+//	@Success		200		{object}	[]store.FollowEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/following [get]
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.dbStore.Followers.GetFollowing)
+}
+
+func (app *application) listFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(context.Context, int64, store.PaginatedFollowQuery) ([]store.FollowEntry, error),
+) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	fq := store.PaginatedFollowQuery{
+		Limit: 20,
+	}
+
+	fq, err = fq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getUser(ctx, userID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	entries, err := list(ctx, userID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, err := store.NextFollowCursor(entries, fq.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, entries, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 // ActivateUser godoc
 //
 //	@Summary		Activates/Register a user
@@ -151,6 +257,67 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// resendActivationHandler godoc
+//
+//	@Summary		Resends an activation email
+//	@Description	Replaces the invitation of an inactive user and emails it again. The response
+//	@Description	is the same whether the email belongs to an inactive account or not.
+//	@Tags			users
+//	@Accept			json
+//	@Param			payload	body	ResendActivationPayload	true	"Account email"
+//	@Success		202		"Invitation resent"
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/users/activate/resend [post]
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	// Limit per address so a mailbox cannot be flooded from many clients. Every address has the
+	// same budget, so the limit does not reveal whether it belongs to an account.
+	if app.config.rateLimiter.Enabled {
+		key := "email-" + strings.ToLower(payload.Email)
+		decision := app.rateLimiter.Allow(rateLimitPolicyResend, ratelimiter.TierUser, key)
+		if !decision.Allowed {
+			app.rateLimitExceeededError(w, r, durationToSeconds(decision.RetryAfter))
+			return
+		}
+	}
+
+	plainToken := uuid.New().String()
+	invitation := func(user *store.User) (*store.OutboxEmail, error) {
+		return app.invitationEmail(user, plainToken)
+	}
+
+	if err := app.dbStore.Users.RotateInvitation(
+		r.Context(), payload.Email, hashToken(plainToken), app.config.mail.exp, invitation,
+	); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			// NOTE: do not reveal whether the email belongs to an inactive account
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func getUserFromContext(r *http.Request) *store.User {
 	user, _ := r.Context().Value(userCtx).(*store.User)
 	return user
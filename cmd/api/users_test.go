@@ -3,8 +3,13 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/ratelimiter"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/atomicmeganerd/gopher-social/internal/store/cache"
 	"github.com/stretchr/testify/mock"
 )
@@ -102,3 +107,92 @@ func TestGetUser(t *testing.T) {
 		mockCacheStore.Calls = nil
 	})
 }
+
+func TestGetFollowers(t *testing.T) {
+	app := newTestApp(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1/followers", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should list followers and following", func(t *testing.T) {
+		for _, path := range []string{"/v1/users/1/followers", "/v1/users/1/following"} {
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := execMockRequests(req, mux)
+			checkResponseCode(t, http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1/followers?cursor=nope", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := execMockRequests(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestResendActivation(t *testing.T) {
+	cfg := config{
+		rateLimiter: ratelimiter.Config{
+			RequestsPerTimeFrame: 20,
+			TimeFrame:            time.Minute,
+			Enabled:              true,
+			Policies: map[string]ratelimiter.Policy{
+				rateLimitPolicyResend: {RequestsPerTimeFrame: 2, TimeFrame: time.Hour},
+			},
+		},
+	}
+
+	app := newTestApp(t, cfg)
+	mux := app.mount()
+
+	resend := func(email, remoteAddr string) int {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/v1/users/activate/resend",
+			strings.NewReader(fmt.Sprintf(`{"email": %q}`, email)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = remoteAddr
+		return execMockRequests(req, mux).Code
+	}
+
+	t.Run("should resend the invitation", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend("gopher@example.com", "10.0.0.1:1234"))
+
+		enqueued := app.dbStore.Outbox.(*store.MockOutboxStore).Enqueued
+		if len(enqueued) != 1 || enqueued[0].Template != mailer.UserWelcomeTemplate {
+			t.Errorf("expected an invitation email but got %+v", enqueued)
+		}
+	})
+
+	t.Run("should limit the requests per email", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend("Gopher@example.com", "10.0.0.2:1234"))
+		checkResponseCode(
+			t, http.StatusTooManyRequests, resend("gopher@example.com", "10.0.0.3:1234"),
+		)
+		checkResponseCode(t, http.StatusAccepted, resend("other@example.com", "10.0.0.3:1234"))
+	})
+}
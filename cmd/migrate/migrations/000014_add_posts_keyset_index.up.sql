@@ -0,0 +1,3 @@
+-- Description: B-tree index backing keyset (cursor) pagination of the feed, which orders and
+-- seeks on the (created_at, id) pair instead of using OFFSET.
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at, id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_post_id_parent_id;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,5 @@
+ALTER TABLE comments
+ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES comments (id) ON DELETE CASCADE;
+
+-- Description: Replies are always loaded per post and per parent ordered by time.
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_parent_id ON comments (post_id, parent_id, created_at);
@@ -0,0 +1,11 @@
+-- A user has at most one reaction per post, reacting again replaces the kind.
+CREATE TABLE IF NOT EXISTS post_reactions (
+  post_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  kind varchar(20) NOT NULL CHECK (kind IN ('like', 'love', 'haha', 'wow', 'sad', 'angry')),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (post_id, user_id),
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,26 @@
+-- A session is started when a user logs in, every access token carries its id so that revoking
+-- the session (logout, password change) invalidates all the tokens issued for it.
+CREATE TABLE IF NOT EXISTS sessions (
+  id uuid PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+  expiry timestamp(0) with time zone NOT NULL,
+  revoked_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- Refresh tokens are stored hashed and rotated on every use. A token that was already used
+-- is never valid again, presenting it revokes the whole session as it was likely stolen.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  token text PRIMARY KEY,
+  session_id uuid NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+  expiry timestamp(0) with time zone NOT NULL,
+  used_at timestamp(0) with time zone
+);
+
+-- Access tokens revoked before they expire, by their jti claim
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti uuid PRIMARY KEY,
+  expiry timestamp(0) with time zone NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+  token text PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  expiry timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
@@ -0,0 +1,19 @@
+-- Emails are written here in the same transaction as the change that triggers them and delivered
+-- by a background worker, see internal/outbox.
+CREATE TABLE IF NOT EXISTS email_outbox (
+  id bigserial PRIMARY KEY,
+  template text NOT NULL,
+  username text NOT NULL,
+  email citext NOT NULL,
+  data jsonb,
+  is_sandbox boolean NOT NULL DEFAULT false,
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+  attempts int NOT NULL DEFAULT 0,
+  last_error text,
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+  sent_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox (next_attempt_at)
+WHERE status = 'pending';
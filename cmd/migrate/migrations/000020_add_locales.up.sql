@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale varchar(10) NOT NULL DEFAULT 'en';
+
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS locale varchar(10) NOT NULL DEFAULT 'en';
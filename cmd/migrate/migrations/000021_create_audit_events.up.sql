@@ -0,0 +1,17 @@
+-- Every administrative change is recorded here. The actor is kept as a plain id so the trail
+-- survives the deletion of the accounts it mentions.
+CREATE TABLE IF NOT EXISTS audit_events (
+  id bigserial PRIMARY KEY,
+  actor_id bigint,
+  action text NOT NULL,
+  target_type text NOT NULL,
+  target_id bigint NOT NULL,
+  request_id text,
+  ip text,
+  before jsonb,
+  after jsonb,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_events_action;
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
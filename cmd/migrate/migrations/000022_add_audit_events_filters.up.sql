@@ -0,0 +1,3 @@
+-- The audit trail is read by the admins filtered by actor or action, newest first.
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, created_at);
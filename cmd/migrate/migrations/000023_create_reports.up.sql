@@ -0,0 +1,28 @@
+-- Hidden content is kept for the moderators but no longer shown to the users.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+
+-- Reports of posts and comments, handled by the moderators in the order they arrive. A user can
+-- report the same content only once.
+CREATE TABLE IF NOT EXISTS reports (
+  id bigserial PRIMARY KEY,
+  target_type text NOT NULL CHECK (target_type IN ('post', 'comment')),
+  target_id bigint NOT NULL,
+  reporter_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  reason text NOT NULL CHECK (
+    reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'misinformation', 'other')
+  ),
+  details text NOT NULL DEFAULT '',
+  status text NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'resolved')),
+  claimed_by bigint REFERENCES users (id) ON DELETE SET NULL,
+  claimed_at timestamp(0) with time zone,
+  resolution text CHECK (resolution IN ('dismiss', 'hide', 'delete')),
+  resolution_note text NOT NULL DEFAULT '',
+  resolved_by bigint REFERENCES users (id) ON DELETE SET NULL,
+  resolved_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+
+  UNIQUE (target_type, target_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_queue ON reports (status, created_at, id);
@@ -0,0 +1,4 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_at;
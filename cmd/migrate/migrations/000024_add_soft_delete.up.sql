@@ -0,0 +1,10 @@
+-- Deleted posts and comments are kept for a retention period so that they can be restored, then
+-- they are purged by the API server.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at)
+WHERE deleted_at IS NOT NULL;
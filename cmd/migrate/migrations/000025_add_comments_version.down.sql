@@ -0,0 +1,2 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS version;
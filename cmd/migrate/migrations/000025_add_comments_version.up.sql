@@ -0,0 +1,4 @@
+-- Comments are edited with optimistic locking like posts, see 000006.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS version int NOT NULL DEFAULT 0;
+ALTER TABLE comments
+ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT now();
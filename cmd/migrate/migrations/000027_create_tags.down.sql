@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tag_followers;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,38 @@
+-- Tags are stored case folded. posts.tags keeps the names of the tags linked in post_tags so the
+-- posts can still be read and filtered without joining them.
+CREATE TABLE IF NOT EXISTS tags (
+  id bigserial PRIMARY KEY,
+  name varchar(100) NOT NULL UNIQUE CHECK (name = lower(name)),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  tag_id bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+
+  PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag_id ON post_tags (tag_id, post_id);
+
+-- The posts with the tags a user follows show up in its feed.
+CREATE TABLE IF NOT EXISTS tag_followers (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  tag_id bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+
+  PRIMARY KEY (user_id, tag_id)
+);
+
+-- Fold the existing tags and link them
+UPDATE posts p SET tags = coalesce((
+  SELECT array_agg(DISTINCT lower(btrim(t))) FROM unnest(p.tags) t WHERE btrim(t) <> ''
+), '{}');
+
+INSERT INTO tags (name)
+SELECT DISTINCT unnest(tags) FROM posts
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO post_tags (post_id, tag_id)
+SELECT p.id, t.id FROM posts p JOIN tags t ON t.name = ANY(p.tags)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- The mentions and hashtags parsed from the content when it is written, see store.Entity. The
+-- existing content has none until it is edited.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS entities jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS entities jsonb NOT NULL DEFAULT '[]';
+
+-- The users mentioned by a post or a comment
+CREATE TABLE IF NOT EXISTS mentions (
+  post_id bigint REFERENCES posts (id) ON DELETE CASCADE,
+  comment_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+
+  CHECK ((post_id IS NULL) <> (comment_id IS NULL)),
+  UNIQUE (post_id, user_id),
+  UNIQUE (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id, created_at);
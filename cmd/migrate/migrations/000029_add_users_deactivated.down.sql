@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_by;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
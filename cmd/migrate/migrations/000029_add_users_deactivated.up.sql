@@ -0,0 +1,5 @@
+-- Deactivation by an admin is kept apart from is_active, which tells if the user confirmed its
+-- email, so that a deactivated user cannot activate its account again with a new invitation.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at timestamp(0) with time zone;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_by bigint
+  REFERENCES users (id) ON DELETE SET NULL;
@@ -1,8 +1,21 @@
 package auth
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrTokenRevoked = errors.New("token has been revoked")
 
 type Authenticator interface {
 	GenerateToken(claims jwt.Claims) (string, error)
 	ValidateToken(token string) (*jwt.Token, error)
 }
+
+// RevocationChecker tells whether an access token was revoked, by its jti claim or by the
+// session (sid claim) it was issued for.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+}
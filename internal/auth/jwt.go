@@ -1,27 +1,64 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Bounds the revocation lookup done on every authenticated request
+const revocationTimeout = time.Second * 2
+
+// JWTAuthenticator signs tokens either with a shared HMAC secret or, when it has a key set, with
+// the active asymmetric key so that other services can verify tokens with the published keys.
 type JWTAuthenticator struct {
-	secret   string // WARNING: Private key, do not expose
-	audience string
-	issuer   string
+	secret      string // WARNING: Private key, do not expose
+	keys        *KeySet
+	audience    string
+	issuer      string
+	revocations RevocationChecker
+}
+
+func NewJWTAuthenticator(
+	secret, audience, issuer string, revocations RevocationChecker,
+) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		secret:      secret,
+		audience:    audience,
+		issuer:      issuer,
+		revocations: revocations,
+	}
 }
 
-func NewJWTAuthenticator(secret, audience, issuer string) *JWTAuthenticator {
+// NewAsymmetricJWTAuthenticator signs tokens with the active key of the set (RS256 or EdDSA) and
+// verifies them with the key matching their kid header.
+func NewAsymmetricJWTAuthenticator(
+	keys *KeySet, audience, issuer string, revocations RevocationChecker,
+) *JWTAuthenticator {
 	return &JWTAuthenticator{
-		secret:   secret,
-		audience: audience,
-		issuer:   issuer,
+		keys:        keys,
+		audience:    audience,
+		issuer:      issuer,
+		revocations: revocations,
 	}
 }
 
+// Keys returns the key set of the authenticator, nil when tokens are signed with a shared secret
+func (a *JWTAuthenticator) Keys() *KeySet {
+	return a.keys
+}
+
 func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	if a.keys != nil {
+		key := a.keys.Active()
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.private)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// Make sure the secret is configured otherwise we might sign tokens with an empty key
@@ -54,7 +91,67 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 // attack: if a secret key was provided, then token verification will fail for tokens using the
 // none algorithm. This is a good idea, but it doesn't solve the underlying problem: attackers
 // control the choice of algorithm. Let's keep digging.
+//
+// On top of the signature and registered claims, tokens must carry a jti and sid claim that have
+// not been revoked by a logout or a password change.
 func (a *JWTAuthenticator) ValidateToken(tokenString string) (*jwt.Token, error) {
+	token, err := a.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.revocations == nil {
+		return token, nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("unexpected claims type")
+	}
+
+	jti, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if jti == "" || sessionID == "" {
+		return nil, errors.New("token is missing the jti or sid claim")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revocationTimeout)
+	defer cancel()
+
+	revoked, err := a.revocations.IsRevoked(ctx, jti, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return token, nil
+}
+
+func (a *JWTAuthenticator) parse(tokenString string) (*jwt.Token, error) {
+	if a.keys != nil {
+		return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+			// The kid header picks the key, a token signed with a removed key is no longer valid
+			kid, _ := token.Header["kid"].(string)
+			key, err := a.keys.Get(kid)
+			if err != nil {
+				return nil, err
+			}
+
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+
+			return key.public, nil
+		},
+			jwt.WithExpirationRequired(),
+			jwt.WithAudience(a.audience),
+			jwt.WithIssuer(a.issuer),
+			jwt.WithValidMethods(a.keys.Methods()),
+		)
+	}
+
 	return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
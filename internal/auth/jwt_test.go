@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testHost = "test-host"
+
+func newTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": int64(1),
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iss": testHost,
+		"aud": testHost,
+	}
+}
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newKeySet(t *testing.T, active string, keys ...*Key) *KeySet {
+	t.Helper()
+
+	ks, err := NewKeySet(active, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestAsymmetricJWTAuthenticator(t *testing.T) {
+	for name, newKey := range map[string]func(*testing.T, string) *Key{
+		"RS256": newRSAKey,
+		"EdDSA": newEd25519Key,
+	} {
+		t.Run(name, func(t *testing.T) {
+			key := newKey(t, "key-1")
+			a := NewAsymmetricJWTAuthenticator(newKeySet(t, "key-1", key), testHost, testHost, nil)
+
+			token, err := a.GenerateToken(newTestClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := a.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("expected the token to be valid: %v", err)
+			}
+			if parsed.Header["kid"] != "key-1" || parsed.Method.Alg() != name {
+				t.Errorf("unexpected header %v", parsed.Header)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "old")
+	newKey := newEd25519Key(t, "new")
+
+	before := NewAsymmetricJWTAuthenticator(newKeySet(t, "old", oldKey), testHost, testHost, nil)
+	oldToken, err := before.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	during := NewAsymmetricJWTAuthenticator(
+		newKeySet(t, "new", oldKey, newKey), testHost, testHost, nil,
+	)
+	if _, err := during.ValidateToken(oldToken); err != nil {
+		t.Errorf("expected tokens of the previous key to stay valid: %v", err)
+	}
+
+	newToken, err := during.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := before.ValidateToken(newToken); err == nil {
+		t.Error("expected tokens of a key not yet in the set to be rejected")
+	}
+
+	after := NewAsymmetricJWTAuthenticator(newKeySet(t, "new", newKey), testHost, testHost, nil)
+	if _, err := after.ValidateToken(oldToken); err == nil {
+		t.Error("expected tokens of a removed key to be rejected")
+	}
+	if _, err := after.ValidateToken(newToken); err != nil {
+		t.Errorf("expected the token to be valid: %v", err)
+	}
+}
+
+func TestRejectsHMACTokensWithKeySet(t *testing.T) {
+	hmac := NewJWTAuthenticator("secret", testHost, testHost, nil)
+	token, err := hmac.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAsymmetricJWTAuthenticator(
+		newKeySet(t, "key-1", newRSAKey(t, "key-1")), testHost, testHost, nil,
+	)
+	if _, err := a.ValidateToken(token); err == nil {
+		t.Error("expected a token signed with a shared secret to be rejected")
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey := newRSAKey(t, "b")
+	edKey := newEd25519Key(t, "a")
+
+	t.Run("active key must be able to sign", func(t *testing.T) {
+		public, err := NewKey("public", edKey.public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewKeySet("public", public); err == nil {
+			t.Error("expected an error for a public active key")
+		}
+		if _, err := NewKeySet("missing", rsaKey); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("expected an unknown key error but got %v", err)
+		}
+	})
+
+	t.Run("publishes the public keys", func(t *testing.T) {
+		jwks := newKeySet(t, "b", rsaKey, edKey).JWKS()
+		if len(jwks.Keys) != 2 {
+			t.Fatalf("expected 2 keys but got %d", len(jwks.Keys))
+		}
+
+		ed, rsa := jwks.Keys[0], jwks.Keys[1]
+		if ed.KeyID != "a" || ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.X == "" {
+			t.Errorf("unexpected Ed25519 key %+v", ed)
+		}
+		if rsa.KeyID != "b" || rsa.KeyType != "RSA" || rsa.Exponent != "AQAB" || rsa.Modulus == "" {
+			t.Errorf("unexpected RSA key %+v", rsa)
+		}
+	})
+
+	t.Run("parses PEM keys", func(t *testing.T) {
+		der, err := x509.MarshalPKCS8PrivateKey(edKey.private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParsePEMKey("a", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.private == nil || key.Method != jwt.SigningMethodEdDSA {
+			t.Errorf("unexpected key %+v", key)
+		}
+
+		der, err = x509.MarshalPKIXPublicKey(rsaKey.public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err = ParsePEMKey("b", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.private != nil || key.Method != jwt.SigningMethodRS256 {
+			t.Errorf("unexpected key %+v", key)
+		}
+	})
+}
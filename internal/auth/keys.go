@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is an asymmetric key identified by the kid header of the tokens it signs. Keys loaded from a
+// public key can only verify tokens, which is how retired keys are kept around during a rotation.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the keys tokens are verified with and the active one new tokens are signed with.
+//
+// Rotating keys works in three steps: add the new key to the set, make it the active one once all
+// the verifying services have picked it up from the JWKS endpoint, then remove the old key once the
+// last token it signed has expired.
+type KeySet struct {
+	active string
+	keys   map[string]*Key
+}
+
+func NewKeySet(activeID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{
+		active: activeID,
+		keys:   make(map[string]*Key, len(keys)),
+	}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	active, ok := ks.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: active key %q is not in the key set", ErrUnknownKey, activeID)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+
+	return ks, nil
+}
+
+// LoadKeySet loads every PEM file of a directory, the file name without its extension being the
+// key id. Private keys can sign and verify tokens, public keys can only verify them.
+func LoadKeySet(dir, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := ParsePEMKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(activeID, keys...)
+}
+
+// ParsePEMKey parses an RSA or Ed25519 key, either a private key in PKCS #8 (or PKCS #1 for RSA)
+// form or a PKIX public key.
+func ParsePEMKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKey(id, parsed)
+}
+
+// NewKey wraps an RSA or Ed25519 private or public key
+func NewKey(id string, k any) (*Key, error) {
+	key := &Key{ID: id}
+
+	switch k := k.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", k)
+	}
+
+	return key, nil
+}
+
+func (ks *KeySet) Active() *Key {
+	return ks.keys[ks.active]
+}
+
+func (ks *KeySet) Get(id string) (*Key, error) {
+	key, ok := ks.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// Methods lists the signing methods of the keys, tokens signed any other way are rejected
+func (ks *KeySet) Methods() []string {
+	seen := map[string]bool{}
+	methods := []string{}
+	for _, key := range ks.keys {
+		if name := key.Method.Alg(); !seen[name] {
+			seen[name] = true
+			methods = append(methods, name)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// JWK is the public part of a key as published in a JSON Web Key Set (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set sorted by id
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(
+				big.NewInt(int64(pub.E)).Bytes(),
+			)
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+
+	return jwks
+}
@@ -189,7 +189,7 @@ func Seed(store *store.Storage, db *pgxpool.Pool) {
 
 	posts := generatePosts(200, users)
 	for _, post := range posts {
-		if err := store.Posts.Create(ctx, post); err != nil {
+		if err := store.Posts.Create(ctx, post, nil); err != nil {
 			slog.Error("failed to create post", "error", err)
 			return
 		}
@@ -197,7 +197,7 @@ func Seed(store *store.Storage, db *pgxpool.Pool) {
 
 	comments := generateComments(500, users, posts)
 	for _, comment := range comments {
-		if err := store.Comments.Create(ctx, comment); err != nil {
+		if err := store.Comments.Create(ctx, comment, nil); err != nil {
 			slog.Error("failed to create comment", "error", err)
 			return
 		}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when neither the request nor the user asks for a supported locale, and for
+// the messages a catalog does not translate.
+const DefaultLocale = "en"
+
+//go:embed "locales"
+var FS embed.FS
+
+// Catalog holds the translated messages of every supported locale, one JSON file per locale
+// mapping message keys to fmt format strings.
+type Catalog struct {
+	messages map[string]map[string]string
+	locales  []string
+	matcher  language.Matcher
+}
+
+func NewCatalog(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{messages: make(map[string]map[string]string, len(files))}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file, err)
+		}
+
+		locale := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		c.messages[locale] = messages
+	}
+
+	defaults, ok := c.messages[DefaultLocale]
+	if !ok {
+		return nil, fmt.Errorf("catalog of the default locale %q is missing", DefaultLocale)
+	}
+
+	others := []string{}
+	for locale, messages := range c.messages {
+		for key := range messages {
+			if _, ok := defaults[key]; !ok {
+				return nil, fmt.Errorf("catalog %s: unknown message %q", locale, key)
+			}
+		}
+		if locale != DefaultLocale {
+			others = append(others, locale)
+		}
+	}
+	sort.Strings(others)
+
+	// The default locale comes first so the matcher falls back to it
+	c.locales = append([]string{DefaultLocale}, others...)
+	tags := make([]language.Tag, 0, len(c.locales))
+	for _, locale := range c.locales {
+		tags = append(tags, language.Make(locale))
+	}
+	c.matcher = language.NewMatcher(tags)
+
+	return c, nil
+}
+
+// Locales lists the supported locales, the default one first
+func (c *Catalog) Locales() []string {
+	return c.locales
+}
+
+func (c *Catalog) Supported(locale string) bool {
+	_, ok := c.messages[locale]
+	return ok
+}
+
+// Match negotiates the locale of an Accept-Language header. It returns false when the header is
+// empty or asks for no supported locale.
+func (c *Catalog) Match(acceptLanguage string) (string, bool) {
+	if acceptLanguage == "" {
+		return "", false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return "", false
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+
+	return c.locales[index], true
+}
+
+// T translates a message, falling back to the default locale and then to the key itself
+func (c *Catalog) T(locale, key string, args ...any) string {
+	format, ok := c.messages[locale][key]
+	if !ok {
+		format, ok = c.messages[DefaultLocale][key]
+	}
+	if !ok {
+		format = key
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalog(t *testing.T) {
+	c, err := NewCatalog(FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("negotiates the Accept-Language header", func(t *testing.T) {
+		for header, want := range map[string]string{
+			"fr":                       "fr",
+			"fr-CA,fr;q=0.9,en;q=0.8":  "fr",
+			"en-GB,fr;q=0.5":           "en",
+			"de-DE,de;q=0.9,fr;q=0.5":  "fr",
+			"es, en-US;q=0.9, fr;q=.1": "en",
+		} {
+			got, ok := c.Match(header)
+			if !ok || got != want {
+				t.Errorf("%q: expected %s but got %s (%v)", header, want, got, ok)
+			}
+		}
+
+		for _, header := range []string{"", "de", "not a header;;"} {
+			if got, ok := c.Match(header); ok {
+				t.Errorf("%q: expected no match but got %s", header, got)
+			}
+		}
+	})
+
+	t.Run("translates with an English fallback", func(t *testing.T) {
+		if got := c.T("fr", "error.not_found"); got != "ressource introuvable" {
+			t.Errorf("unexpected translation %q", got)
+		}
+		if got := c.T("de", "error.not_found"); got != "resource not found" {
+			t.Errorf("expected the English message but got %q", got)
+		}
+		got := c.T("fr", "error.rate_limit_exceeded", "3")
+		if got != "limite de requêtes dépassée, réessayez dans : 3" {
+			t.Errorf("unexpected translation %q", got)
+		}
+		if got := c.T("fr", "error.unknown"); got != "error.unknown" {
+			t.Errorf("expected the key of unknown messages but got %q", got)
+		}
+	})
+
+	t.Run("every catalog only translates known messages", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"locales/en.json": {Data: []byte(`{"hello": "hello"}`)},
+			"locales/fr.json": {Data: []byte(`{"hello": "bonjour", "bye": "au revoir"}`)},
+		}
+		if _, err := NewCatalog(fsys); err == nil {
+			t.Error("expected an error for the unknown message")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer does not deliver emails, it writes them as .eml files to a directory for inspection
+// or to stdout when no directory is configured. It is meant for development and CI.
+type FileMailer struct {
+	fromEmail string
+	dir       string
+	out       io.Writer
+	templates *Templates
+	mu        sync.Mutex
+}
+
+func NewFile(dir, fromEmail string, templates *Templates) *FileMailer {
+	return &FileMailer{
+		fromEmail: fromEmail,
+		dir:       dir,
+		out:       os.Stdout,
+		templates: templates,
+	}
+}
+
+func (m *FileMailer) Send(
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
+) (int, error) {
+	rendered, err := m.templates.Render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	msg, err := buildMessage(m.fromEmail, username, email, rendered)
+	if err != nil {
+		return -1, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dir == "" {
+		if _, err := fmt.Fprintf(m.out, "%s\n\n", msg); err != nil {
+			return -1, err
+		}
+		return 200, nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return -1, err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.Map(safeFileRune, email))
+	if err := os.WriteFile(filepath.Join(m.dir, name), msg, 0o644); err != nil {
+		return -1, err
+	}
+
+	return 200, nil
+}
+
+func safeFileRune(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return r
+	case r == '@' || r == '.' || r == '-' || r == '_':
+		return r
+	default:
+		return '_'
+	}
+}
@@ -1,16 +1,49 @@
 package mailer
 
-import "embed"
+import (
+	"context"
+	"embed"
+	"time"
+)
 
 const (
-	FromName            = "GopherSocial"
-	maxRetries          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName              = "GopherSocial"
+	maxRetries            = 3
+	UserWelcomeTemplate   = "user_invitation.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
+	MentionTemplate       = "mention.tmpl"
+	// Locale of the templates at the root of the templates directory
+	DefaultLocale = "en"
 )
 
+// How long a single delivery attempt can last, the clients give up after it
+const attemptTimeout = 30 * time.Second
+
+// MaxSendDuration is the longest a Client.Send can take: every attempt times out and is followed
+// by a delay growing by a second.
+const MaxSendDuration = maxRetries*attemptTimeout + maxRetries*(maxRetries+1)/2*time.Second
+
 //go:embed "templates"
 var FS embed.FS
 
+// Backends of the mailer, see cmd/api/main.go
+const (
+	BackendSendGrid = "sendgrid"
+	BackendSMTP     = "smtp"
+	BackendFile     = "file"
+)
+
+// IsSuccess tells if a status reported by a Client means the email was accepted for delivery,
+// SMTP reports 250 and the HTTP APIs 2xx.
+func IsSuccess(status int) bool {
+	return status >= 200 && status <= 299
+}
+
 type Client interface {
-	Send(templateFile, username, email string, data any, isSandbox bool) (int, error)
+	Send(
+		ctx context.Context,
+		templateFile, locale, username, email string,
+		data any,
+		isSandbox bool,
+	) (int, error)
 }
@@ -0,0 +1,239 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var invitation = struct {
+	Username      string
+	ActivationURL string
+}{
+	Username:      "gopher",
+	ActivationURL: "http://localhost:5173/confirm/some-token",
+}
+
+func newTestTemplates(t *testing.T) *Templates {
+	t.Helper()
+
+	templates, err := NewTemplates(FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// newFakeSMTPServer accepts the commands net/smtp sends without auth nor STARTTLS and records the
+// messages it receives.
+func newFakeSMTPServer(t *testing.T) (string, int, <-chan smtpMessage) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber, messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- smtpMessage) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost fake SMTP")
+
+	var msg smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			msg.from = line
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, line)
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			messages <- msg
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	host, port, messages := newFakeSMTPServer(t)
+	m := NewSMTP(host, port, "", "", "noreply@example.com", newTestTemplates(t))
+
+	status, err := m.Send(
+		context.Background(),
+		UserWelcomeTemplate, DefaultLocale, "gopher", "gopher@example.com", invitation, true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 250 {
+		t.Errorf("expected status 250 but got %d", status)
+	}
+
+	msg := <-messages
+	if !strings.Contains(msg.from, "<noreply@example.com>") {
+		t.Errorf("unexpected sender %q", msg.from)
+	}
+	if len(msg.to) != 1 || !strings.Contains(msg.to[0], "<gopher@example.com>") {
+		t.Errorf("unexpected recipients %q", msg.to)
+	}
+
+	for _, want := range []string{
+		"Subject: Finish Registration with GopherSocial",
+		`To: "gopher" <gopher@example.com>`,
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain",
+		"Content-Type: text/html",
+		invitation.ActivationURL,
+	} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("expected %q in the message:\n%s", want, msg.data)
+		}
+	}
+}
+
+func TestSMTPMailerStalledServer(t *testing.T) {
+	// The server accepts the connection but never greets the client
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	m := NewSMTP(host, portNumber, "", "", "noreply@example.com", newTestTemplates(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := m.Send(
+		ctx, UserWelcomeTemplate, DefaultLocale, "gopher", "gopher@example.com", invitation, true,
+	); err == nil {
+		t.Fatal("expected an error from a stalled server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the delivery to give up with the context but it took %s", elapsed)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	t.Run("writes the emails to a directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "mail")
+		m := NewFile(dir, "noreply@example.com", newTestTemplates(t))
+
+		if _, err := m.Send(
+			context.Background(),
+			UserWelcomeTemplate, DefaultLocale, "gopher", "gopher@example.com", invitation, true,
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := filepath.Glob(filepath.Join(dir, "*gopher@example.com.eml"))
+		if err != nil || len(files) != 1 {
+			t.Fatalf("expected one email file but got %v (%v)", files, err)
+		}
+
+		data, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(invitation.ActivationURL)) {
+			t.Errorf("expected the activation url in the email:\n%s", data)
+		}
+	})
+
+	t.Run("writes the emails to stdout without a directory", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		m := NewFile("", "noreply@example.com", newTestTemplates(t))
+		m.out = out
+
+		if _, err := m.Send(
+			context.Background(),
+			UserWelcomeTemplate, DefaultLocale, "gopher", "gopher@example.com", invitation, true,
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		r := textproto.NewReader(bufio.NewReader(out))
+		header, err := r.ReadMIMEHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get("Subject"); got != "Finish Registration with GopherSocial" {
+			t.Errorf("unexpected subject %q", got)
+		}
+	})
+
+	t.Run("fails on unknown templates", func(t *testing.T) {
+		m := NewFile("", "noreply@example.com", newTestTemplates(t))
+		m.out = new(bytes.Buffer)
+
+		if _, err := m.Send(
+			context.Background(), "missing.tmpl", "en", "gopher", "gopher@example.com", nil, true,
+		); err == nil {
+			t.Error("expected an error for an unknown template")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"time"
+)
+
+// buildMessage formats an email as an RFC 5322 message with text and HTML alternatives, as sent
+// over SMTP or written to an .eml file.
+func buildMessage(fromEmail, username, email string, rendered *Message) ([]byte, error) {
+	from := mail.Address{Name: FromName, Address: fromEmail}
+	to := mail.Address{Name: username, Address: email}
+
+	body := new(bytes.Buffer)
+	parts := multipart.NewWriter(body)
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", rendered.Subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%q\r\n", parts.Boundary())
+	msg.WriteString("\r\n")
+
+	// Clients show the last alternative they support, so the HTML part goes last
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=\"utf-8\"", rendered.Text},
+		{"text/html; charset=\"utf-8\"", rendered.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MockMailer records the emails it was asked to send instead of sending them
+type MockMailer struct {
+	mu   sync.Mutex
+	Sent []MockEmail
+}
+
+type MockEmail struct {
+	TemplateFile string
+	Locale       string
+	Username     string
+	Email        string
+	Data         any
+}
+
+// WARNING: Do not use this method in production it is for unit tests only
+func (m *MockMailer) Send(
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
+) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Sent = append(m.Sent, MockEmail{
+		TemplateFile: templateFile,
+		Locale:       locale,
+		Username:     username,
+		Email:        email,
+		Data:         data,
+	})
+	return 200, nil
+}
@@ -1,9 +1,8 @@
 package mailer
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"html/template"
 	"time"
 
 	"github.com/sendgrid/sendgrid-go"
@@ -14,42 +13,33 @@ type SendGridMailer struct {
 	fromEmail string
 	apiKey    string
 	client    *sendgrid.Client
+	templates *Templates
 }
 
-func NewSendgrid(apiKey, fromEmail string) *SendGridMailer {
+func NewSendgrid(apiKey, fromEmail string, templates *Templates) *SendGridMailer {
 	client := sendgrid.NewSendClient(apiKey)
 
 	return &SendGridMailer{
 		fromEmail: fromEmail,
 		apiKey:    apiKey,
 		client:    client,
+		templates: templates,
 	}
 }
 
 func (m *SendGridMailer) Send(
-	templateFile, username, email string, data any, isSandbox bool,
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
 ) (int, error) {
 
 	from := mail.NewEmail(FromName, m.fromEmail)
 	to := mail.NewEmail(username, email)
 
-	temp, err := template.ParseFS(FS, fmt.Sprintf("templates/%s", UserWelcomeTemplate))
-	if err != nil {
-		return -1, nil
-	}
-
-	subject := new(bytes.Buffer)
-	err = temp.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return -1, err
-	}
-	body := new(bytes.Buffer)
-	err = temp.ExecuteTemplate(body, "body", data)
+	msg, err := m.templates.Render(templateFile, locale, data)
 	if err != nil {
 		return -1, err
 	}
 
-	message := mail.NewSingleEmail(from, subject.String(), to, "", body.String())
+	message := mail.NewSingleEmail(from, msg.Subject, to, msg.Text, msg.HTML)
 
 	message.SetMailSettings(&mail.MailSettings{
 		SandboxMode: &mail.Setting{
@@ -59,12 +49,25 @@ func (m *SendGridMailer) Send(
 
 	var retryErr error
 	for i := range maxRetries {
-		response, retryErr := m.client.Send(message)
-		if retryErr != nil {
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
+		attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+		response, err := m.client.SendWithContext(attemptCtx, message)
+		cancel()
+		if err == nil && !IsSuccess(response.StatusCode) {
+			// SendGrid rejects the email with an HTTP status, e.g. for a bad API key or throttling
+			err = fmt.Errorf(
+				"sendgrid answered with status %d: %s", response.StatusCode, response.Body,
+			)
+		}
+		if err == nil {
+			return response.StatusCode, nil
+		}
+
+		retryErr = err
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-time.After(time.Second * time.Duration(i+1)):
 		}
-		return response.StatusCode, nil
 	}
 	return -1, fmt.Errorf("failed to send email after %d attempts, error: %v", maxRetries, retryErr)
 }
@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer sends emails through any SMTP server, e.g. a local Mailpit in development. STARTTLS
+// is used when the server offers it.
+type SMTPMailer struct {
+	fromEmail string
+	host      string
+	addr      string
+	auth      smtp.Auth
+	templates *Templates
+}
+
+func NewSMTP(
+	host string, port int, username, password, fromEmail string, templates *Templates,
+) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		// NOTE: PlainAuth refuses to send the credentials over an unencrypted connection unless
+		// the server is on localhost
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		fromEmail: fromEmail,
+		host:      host,
+		addr:      net.JoinHostPort(host, strconv.Itoa(port)),
+		auth:      auth,
+		templates: templates,
+	}
+}
+
+// Send ignores isSandbox, point the mailer at a test SMTP server to avoid delivering emails.
+func (m *SMTPMailer) Send(
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
+) (int, error) {
+	rendered, err := m.templates.Render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	msg, err := buildMessage(m.fromEmail, username, email, rendered)
+	if err != nil {
+		return -1, err
+	}
+
+	var sendErr error
+	for i := range maxRetries {
+		sendErr = m.send(ctx, email, msg)
+		if sendErr == nil {
+			// SMTP has no HTTP status, report the status of a successful delivery
+			return 250, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-time.After(time.Second * time.Duration(i+1)):
+		}
+	}
+	return -1, fmt.Errorf("failed to send email after %d attempts, error: %v", maxRetries, sendErr)
+}
+
+// send delivers the message in a single SMTP session, like smtp.SendMail, but gives up after
+// attemptTimeout or when the context is done so that a stalled server cannot block the caller.
+func (m *SMTPMailer) send(ctx context.Context, to string, msg []byte) error {
+	dialer := net.Dialer{Timeout: attemptTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(attemptTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	// Expire the deadline to interrupt the session as soon as the context is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("the SMTP server does not support authentication")
+		}
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.fromEmail); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,178 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// Every email template must define these, the layouts turn the contents into the text and HTML
+// parts of the email.
+var requiredTemplates = []string{"subject", "text_content", "html_content"}
+
+const (
+	layoutsGlob  = "templates/layouts/*.tmpl"
+	partialsGlob = "templates/partials/*.tmpl"
+	emailsGlob   = "templates/*.tmpl"
+	localesGlob  = "templates/locales/*"
+)
+
+// Message is a rendered email
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Templates is the registry of the email templates. Every email is parsed with the shared layouts
+// and partials, once as text and once as HTML so that only the HTML part is escaped.
+//
+// Translations live in templates/locales/<locale>, with their own partials overriding the shared
+// ones. Emails that are not translated fall back to the default locale.
+type Templates struct {
+	text map[string]map[string]*texttemplate.Template
+	html map[string]map[string]*htmltemplate.Template
+}
+
+// NewTemplates parses and validates all the email templates of fsys, so that a broken template
+// fails at startup rather than when the email is sent.
+func NewTemplates(fsys fs.FS) (*Templates, error) {
+	shared, err := globAll(fsys, layoutsGlob, partialsGlob)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, err := fs.Glob(fsys, emailsGlob)
+	if err != nil {
+		return nil, err
+	}
+	if len(emails) == 0 {
+		return nil, errors.New("no email templates found")
+	}
+
+	t := &Templates{
+		text: map[string]map[string]*texttemplate.Template{},
+		html: map[string]map[string]*htmltemplate.Template{},
+	}
+	for _, email := range emails {
+		if err := t.add(fsys, DefaultLocale, email, shared); err != nil {
+			return nil, err
+		}
+	}
+
+	locales, err := fs.Glob(fsys, localesGlob)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range locales {
+		locale := path.Base(dir)
+
+		// The localized partials are parsed last so they replace the shared ones
+		localized, err := globAll(fsys, path.Join(dir, "partials/*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		localized = append(append([]string{}, shared...), localized...)
+
+		emails, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, email := range emails {
+			if _, ok := t.text[DefaultLocale][path.Base(email)]; !ok {
+				return nil, fmt.Errorf("template %s has no %s version", email, DefaultLocale)
+			}
+			if err := t.add(fsys, locale, email, localized); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t, nil
+}
+
+func (t *Templates) add(fsys fs.FS, locale, email string, shared []string) error {
+	name := path.Base(email)
+	files := append([]string{email}, shared...)
+
+	text, err := texttemplate.New(name).ParseFS(fsys, files...)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", email, err)
+	}
+	html, err := htmltemplate.New(name).ParseFS(fsys, files...)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", email, err)
+	}
+
+	for _, required := range requiredTemplates {
+		if text.Lookup(required) == nil {
+			return fmt.Errorf("template %s: %q is not defined", email, required)
+		}
+	}
+	if text.Lookup("text") == nil || html.Lookup("html") == nil {
+		return fmt.Errorf("template %s: the text and html layouts are not defined", email)
+	}
+
+	if t.text[locale] == nil {
+		t.text[locale] = map[string]*texttemplate.Template{}
+		t.html[locale] = map[string]*htmltemplate.Template{}
+	}
+	t.text[locale][name] = text
+	t.html[locale][name] = html
+
+	return nil
+}
+
+// Render renders the subject, text and HTML parts of an email template in the given locale
+func (t *Templates) Render(templateFile, locale string, data any) (*Message, error) {
+	if _, ok := t.text[locale][templateFile]; !ok {
+		locale = DefaultLocale
+	}
+
+	text, ok := t.text[locale][templateFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, templateFile)
+	}
+
+	subject := new(bytes.Buffer)
+	if err := text.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	textBody := new(bytes.Buffer)
+	if err := text.ExecuteTemplate(textBody, "text", data); err != nil {
+		return nil, err
+	}
+
+	htmlBody := new(bytes.Buffer)
+	if err := t.html[locale][templateFile].ExecuteTemplate(htmlBody, "html", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		// Headers cannot span several lines
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+		HTML:    strings.TrimSpace(htmlBody.String()) + "\n",
+	}, nil
+}
+
+func globAll(fsys fs.FS, patterns ...string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplates(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	t.Run("renders every template as text and HTML", func(t *testing.T) {
+		data := map[string]any{
+			"Username":      "gopher",
+			"ActivationURL": "http://localhost:5173/confirm/token",
+			"ResetURL":      "http://localhost:5173/reset-password/token",
+			"ExpiresIn":     "1h0m0s",
+			"Author":        "ferris",
+			"Excerpt":       "Hello @gopher",
+			"URL":           "http://localhost:5173/posts/1",
+		}
+
+		for _, name := range []string{UserWelcomeTemplate, PasswordResetTemplate, MentionTemplate} {
+			msg, err := templates.Render(name, DefaultLocale, data)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("%s: unexpected subject %q", name, msg.Subject)
+			}
+			if strings.Contains(msg.Text, "<p>") || !strings.Contains(msg.Text, "GopherSocial Team") {
+				t.Errorf("%s: unexpected text part:\n%s", name, msg.Text)
+			}
+			if !strings.Contains(msg.HTML, "<html>") || !strings.Contains(msg.HTML, "GopherSocial Team") {
+				t.Errorf("%s: unexpected HTML part:\n%s", name, msg.HTML)
+			}
+		}
+	})
+
+	t.Run("only escapes the HTML part", func(t *testing.T) {
+		data := map[string]any{"Username": "<b>gopher</b>"}
+		msg, err := templates.Render(UserWelcomeTemplate, DefaultLocale, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(msg.Text, "<b>gopher</b>") {
+			t.Errorf("expected the text part to be unescaped:\n%s", msg.Text)
+		}
+		if !strings.Contains(msg.HTML, "&lt;b&gt;gopher&lt;/b&gt;") {
+			t.Errorf("expected the HTML part to be escaped:\n%s", msg.HTML)
+		}
+	})
+
+	t.Run("fails on unknown templates", func(t *testing.T) {
+		if _, err := templates.Render("missing.tmpl", "fr", nil); !errors.Is(err, ErrUnknownTemplate) {
+			t.Errorf("expected an unknown template error but got %v", err)
+		}
+	})
+}
+
+func TestNewTemplatesValidation(t *testing.T) {
+	layout, err := FS.ReadFile("templates/layouts/base.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer, err := FS.ReadFile("templates/partials/footer.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"missing text content": `{{define "subject"}}Hi{{end}}{{define "html_content"}}Hi{{end}}`,
+		"syntax error":         `{{define "subject"}}{{.Username{{end}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"templates/layouts/base.tmpl":    {Data: layout},
+				"templates/partials/footer.tmpl": {Data: footer},
+				"templates/broken.tmpl":          {Data: []byte(content)},
+			}
+			if _, err := NewTemplates(fsys); err == nil {
+				t.Error("expected the broken template to be rejected")
+			}
+		})
+	}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	templates := newTestTemplates(t)
+	data := map[string]any{"Username": "gopher", "ActivationURL": "http://localhost/confirm"}
+
+	msg, err := templates.Render(UserWelcomeTemplate, "fr", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Finalisez votre inscription à GopherSocial" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.HTML, "<p>L'équipe GopherSocial</p>") {
+		t.Errorf("expected the localized footer in the HTML part:\n%s", msg.HTML)
+	}
+	if !strings.Contains(msg.Text, "L'équipe GopherSocial") {
+		t.Errorf("expected the localized footer in the text part:\n%s", msg.Text)
+	}
+
+	msg, err = templates.Render(UserWelcomeTemplate, "de", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Finish Registration with GopherSocial" {
+		t.Errorf("expected unknown locales to fall back to English but got %q", msg.Subject)
+	}
+}
@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+// Store is the part of store.Storage the worker needs
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]store.OutboxEmail, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, nextAttempt time.Time, lastErr string) error
+	MarkDead(ctx context.Context, id int64, lastErr string) error
+}
+
+type Config struct {
+	// How often the outbox is polled for due emails
+	PollInterval time.Duration
+	// How many emails are claimed at once
+	BatchSize int
+	// Emails are moved to the dead letters after this many failed attempts
+	MaxAttempts int
+	// Delay before the first retry, doubled after every failed attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Worker delivers the emails of the outbox. It runs inside the API process, several instances can
+// poll the same outbox as claimed emails are leased to a single worker.
+type Worker struct {
+	cfg    Config
+	store  Store
+	mailer mailer.Client
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewWorker(cfg Config, store Store, mailer mailer.Client, logger *slog.Logger) *Worker {
+	return &Worker{
+		cfg:    cfg,
+		store:  store,
+		mailer: mailer,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run polls the outbox until the context is canceled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Info("outbox worker started", "poll interval", w.cfg.PollInterval)
+	for {
+		if err := w.ProcessBatch(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("error processing the email outbox", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("outbox worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch delivers the emails that are due
+func (w *Worker) ProcessBatch(ctx context.Context) error {
+	// The lease must outlast the delivery of the whole batch, including the retries of the mailer,
+	// with a minute to spare for recording the outcomes
+	lease := mailer.MaxSendDuration*time.Duration(w.cfg.BatchSize) + time.Minute
+	emails, err := w.store.Claim(ctx, w.cfg.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		if ctx.Err() != nil {
+			// Unsent emails are picked up again once their lease expires
+			return ctx.Err()
+		}
+		if err := w.deliver(ctx, email); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, email store.OutboxEmail) error {
+	status, sendErr := w.send(ctx, email)
+	if sendErr == nil && !mailer.IsSuccess(status) {
+		// The email was rejected, it must be retried like any failed delivery
+		sendErr = fmt.Errorf("the mailer answered with status %d", status)
+	}
+	if sendErr == nil {
+		w.logger.Info("Email sent", "id", email.ID, "template", email.Template, "status code", status)
+		return w.store.MarkSent(ctx, email.ID)
+	}
+
+	attempt := email.Attempts + 1
+	if attempt >= w.cfg.MaxAttempts {
+		w.logger.Error(
+			"email moved to the dead letters", "id", email.ID, "attempts", attempt, "error", sendErr,
+		)
+		return w.store.MarkDead(ctx, email.ID, sendErr.Error())
+	}
+
+	nextAttempt := w.now().Add(w.backoff(attempt))
+	w.logger.Warn(
+		"email delivery failed", "id", email.ID, "attempts", attempt, "retry at", nextAttempt,
+		"error", sendErr,
+	)
+	return w.store.MarkFailed(ctx, email.ID, nextAttempt, sendErr.Error())
+}
+
+func (w *Worker) send(ctx context.Context, email store.OutboxEmail) (int, error) {
+	// The lease is computed for this bound, the email is claimed again once it expires
+	ctx, cancel := context.WithTimeout(ctx, mailer.MaxSendDuration)
+	defer cancel()
+
+	var data map[string]any
+	if len(email.Data) > 0 {
+		if err := json.Unmarshal(email.Data, &data); err != nil {
+			return -1, err
+		}
+	}
+
+	return w.mailer.Send(
+		ctx,
+		email.Template, email.Locale, email.Username, email.Email, data, email.IsSandbox,
+	)
+}
+
+// backoff is the delay before retrying an email that failed the given number of times
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.cfg.BaseBackoff
+	for range attempt - 1 {
+		delay *= 2
+		if delay >= w.cfg.MaxBackoff {
+			return w.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/mailer"
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+)
+
+type fakeStore struct {
+	lease   time.Duration
+	pending []store.OutboxEmail
+	sent    []int64
+	failed  map[int64]time.Time
+	dead    []int64
+}
+
+func (s *fakeStore) Claim(
+	ctx context.Context, limit int, lease time.Duration,
+) ([]store.OutboxEmail, error) {
+	s.lease = lease
+	claimed := s.pending[:min(limit, len(s.pending))]
+	s.pending = s.pending[len(claimed):]
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkSent(ctx context.Context, id int64) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(
+	ctx context.Context, id int64, nextAttempt time.Time, lastErr string,
+) error {
+	s.failed[id] = nextAttempt
+	return nil
+}
+
+func (s *fakeStore) MarkDead(ctx context.Context, id int64, lastErr string) error {
+	s.dead = append(s.dead, id)
+	return nil
+}
+
+type failingMailer struct{}
+
+func (m *failingMailer) Send(
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
+) (int, error) {
+	return -1, errors.New("mail provider is down")
+}
+
+// rejectingMailer reports the status of an email rejected by the provider without an error
+type rejectingMailer struct{}
+
+func (m *rejectingMailer) Send(
+	ctx context.Context, templateFile, locale, username, email string, data any, isSandbox bool,
+) (int, error) {
+	return 500, nil
+}
+
+var testConfig = Config{
+	PollInterval: time.Second,
+	BatchSize:    2,
+	MaxAttempts:  3,
+	BaseBackoff:  time.Second * 30,
+	MaxBackoff:   time.Minute * 5,
+}
+
+func newTestWorker(s Store, m mailer.Client, now time.Time) *Worker {
+	w := NewWorker(testConfig, s, m, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.now = func() time.Time { return now }
+	return w
+}
+
+func TestProcessBatch(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("delivers the due emails", func(t *testing.T) {
+		s := &fakeStore{
+			pending: []store.OutboxEmail{
+				{ID: 1, Template: mailer.UserWelcomeTemplate, Data: []byte(`{"Username":"gopher"}`)},
+				{ID: 2, Template: mailer.PasswordResetTemplate},
+				{ID: 3, Template: mailer.UserWelcomeTemplate},
+			},
+			failed: map[int64]time.Time{},
+		}
+		m := &mailer.MockMailer{}
+		w := newTestWorker(s, m, now)
+
+		if err := w.ProcessBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if s.lease < 2*mailer.MaxSendDuration {
+			t.Errorf("expected the lease to outlast the delivery of 2 emails but got %v", s.lease)
+		}
+		if len(s.sent) != 2 || len(m.Sent) != 2 {
+			t.Fatalf("expected a batch of 2 emails but sent %v", s.sent)
+		}
+		data, _ := m.Sent[0].Data.(map[string]any)
+		if data["Username"] != "gopher" {
+			t.Errorf("expected the template data to be decoded but got %v", m.Sent[0].Data)
+		}
+
+		if err := w.ProcessBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.sent) != 3 {
+			t.Errorf("expected the remaining email to be sent but sent %v", s.sent)
+		}
+	})
+
+	t.Run("retries with exponential backoff then dead letters", func(t *testing.T) {
+		s := &fakeStore{
+			pending: []store.OutboxEmail{
+				{ID: 1, Attempts: 0},
+				{ID: 2, Attempts: 1},
+			},
+			failed: map[int64]time.Time{},
+		}
+		w := newTestWorker(s, &failingMailer{}, now)
+
+		if err := w.ProcessBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.failed[1]; !got.Equal(now.Add(time.Second * 30)) {
+			t.Errorf("expected the first retry in 30s but got %v", got.Sub(now))
+		}
+		if got := s.failed[2]; !got.Equal(now.Add(time.Minute)) {
+			t.Errorf("expected the second retry in 1m but got %v", got.Sub(now))
+		}
+
+		s.pending = []store.OutboxEmail{{ID: 1, Attempts: 2}}
+		if err := w.ProcessBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.dead) != 1 || s.dead[0] != 1 {
+			t.Errorf("expected the email to be dead lettered but got %v", s.dead)
+		}
+	})
+
+	t.Run("retries the emails rejected with an error status", func(t *testing.T) {
+		s := &fakeStore{
+			pending: []store.OutboxEmail{{ID: 1, Attempts: 0}},
+			failed:  map[int64]time.Time{},
+		}
+		w := newTestWorker(s, &rejectingMailer{}, now)
+
+		if err := w.ProcessBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.sent) != 0 {
+			t.Errorf("expected the rejected email not to be sent but sent %v", s.sent)
+		}
+		if got, ok := s.failed[1]; !ok || !got.Equal(now.Add(time.Second*30)) {
+			t.Errorf("expected the rejected email to be retried in 30s but got %v", s.failed)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	w := newTestWorker(&fakeStore{}, &mailer.MockMailer{}, time.Now())
+
+	for attempt, want := range map[int]time.Duration{
+		1:  time.Second * 30,
+		2:  time.Minute,
+		3:  time.Minute * 2,
+		4:  time.Minute * 4,
+		5:  time.Minute * 5,
+		20: time.Minute * 5,
+	} {
+		if got := w.backoff(attempt); got != want {
+			t.Errorf("attempt %d: expected %v but got %v", attempt, want, got)
+		}
+	}
+}
@@ -6,44 +6,59 @@ import (
 )
 
 type FixedWindowLimiter struct {
-	sync.RWMutex
-	clients map[string]int
+	sync.Mutex
+	clients map[string]*fixedWindow
 	limit   int
 	window  time.Duration
 }
 
+type fixedWindow struct {
+	count   int
+	resetAt time.Time
+}
+
 func NewFixedWindowLimiter(
 	limit int,
 	window time.Duration,
 ) Limiter {
 	return &FixedWindowLimiter{
-		clients: make(map[string]int),
+		clients: make(map[string]*fixedWindow),
 		limit:   limit,
 		window:  window,
 	}
 }
 
-// NOTE: Consider using redis for lookup in rate limiting if you want max performance
-// TODO: Implement this in Redis after if we have time
-func (rl *FixedWindowLimiter) Allow(ip string) (bool, time.Duration) {
-	rl.RLock()
-	count, exists := rl.clients[ip]
-	rl.RUnlock()
+// NOTE: The counts are kept in memory so each API replica allows the full limit per client. Use
+// RedisFixedWindowLimiter when running more than one replica.
+func (rl *FixedWindowLimiter) Allow(ip string) Decision {
+	rl.Lock()
+	defer rl.Unlock()
 
-	if !exists || count < rl.limit {
+	client, exists := rl.clients[ip]
+	if !exists {
+		client = &fixedWindow{resetAt: time.Now().Add(rl.window)}
+		rl.clients[ip] = client
+		go rl.resetCount(ip)
+	}
 
-		rl.Lock()
-		if !exists {
-			go rl.resetCount(ip)
+	resetAfter := time.Until(client.resetAt)
+	if client.count < rl.limit {
+		client.count++
+		return Decision{
+			Allowed:    true,
+			Limit:      rl.limit,
+			Remaining:  rl.limit - client.count,
+			ResetAfter: resetAfter,
 		}
-
-		rl.clients[ip]++
-		rl.Unlock()
-
-		return true, 0
 	}
 
-	return false, rl.window
+	return Decision{
+		Allowed:    false,
+		Limit:      rl.limit,
+		Remaining:  0,
+		ResetAfter: resetAfter,
+		RetryAfter: resetAfter,
+	}
 }
 
 func (rl *FixedWindowLimiter) resetCount(ip string) {
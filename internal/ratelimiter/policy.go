@@ -0,0 +1,69 @@
+package ratelimiter
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPolicy is the budget of the route groups that do not declare one of their own
+const DefaultPolicy = "default"
+
+// Tier scales the budget of a policy for a class of clients
+type Tier string
+
+const (
+	TierUser  Tier = "user"
+	TierAdmin Tier = "admin"
+)
+
+// Policies holds one limiter per policy and tier. Every route group can declare the policy it
+// is limited by so that cheap and expensive routes do not share the same budget.
+type Policies struct {
+	limiters map[string]map[Tier]Limiter
+}
+
+func NewPolicies(cfg Config, rds *redis.Client) (*Policies, error) {
+	budgets := map[string]Policy{
+		DefaultPolicy: {RequestsPerTimeFrame: cfg.RequestsPerTimeFrame, TimeFrame: cfg.TimeFrame},
+	}
+	for name, budget := range cfg.Policies {
+		budgets[name] = budget
+	}
+
+	multipliers := map[Tier]int{
+		TierUser:  1,
+		TierAdmin: max(cfg.AdminMultiplier, 1),
+	}
+
+	p := &Policies{limiters: make(map[string]map[Tier]Limiter)}
+	for name, budget := range budgets {
+		p.limiters[name] = make(map[Tier]Limiter)
+		for tier, multiplier := range multipliers {
+			tierCfg := cfg
+			tierCfg.RequestsPerTimeFrame = budget.RequestsPerTimeFrame * multiplier
+			tierCfg.TimeFrame = budget.TimeFrame
+
+			limiter, err := New(tierCfg, rds)
+			if err != nil {
+				return nil, fmt.Errorf("rate limit policy %s: %w", name, err)
+			}
+			p.limiters[name][tier] = limiter
+		}
+	}
+
+	return p, nil
+}
+
+// Allow counts a request of the client identified by key against the budget of the policy for
+// its tier. Unknown policies fall back to the default policy.
+func (p *Policies) Allow(policy string, tier Tier, key string) Decision {
+	tiers, ok := p.limiters[policy]
+	if !ok {
+		policy = DefaultPolicy
+		tiers = p.limiters[DefaultPolicy]
+	}
+
+	// The limiters of every policy and tier may share the same Redis so namespace the keys
+	return tiers[tier].Allow(fmt.Sprintf("%s:%s:%s", policy, tier, key))
+}
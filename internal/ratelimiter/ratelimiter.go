@@ -1,15 +1,101 @@
 package ratelimiter
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
 
 type Limiter interface {
-	// The duration is how to wait before can make another request
-	// if not allowed return 429 -> header with duration before next request
-	Allow(ip string) (bool, time.Duration)
+	// Allow consumes one request from the budget of the client identified by key.
+	// If not allowed return 429 -> header with duration before next request
+	Allow(key string) Decision
+}
+
+// Decision is the outcome of a rate limit check, it is surfaced to clients in the
+// X-RateLimit-* headers of every response.
+type Decision struct {
+	Allowed bool
+	// Limit is the number of requests allowed per time frame
+	Limit int
+	// Remaining is the number of requests left in the current time frame
+	Remaining int
+	// ResetAfter is the time until the budget is fully restored
+	ResetAfter time.Duration
+	// RetryAfter is how long to wait before the next request is allowed, zero when allowed
+	RetryAfter time.Duration
 }
 
+const (
+	// BackendMemory keeps the counters in the API process, each replica has its own budget
+	BackendMemory = "memory"
+	// BackendRedis keeps the counters in Redis, the budget is shared by all replicas
+	BackendRedis = "redis"
+)
+
+const (
+	// AlgorithmFixedWindow counts requests in consecutive windows. It is the cheapest but a client
+	// can send up to twice the limit across a window boundary.
+	AlgorithmFixedWindow = "fixed-window"
+	// AlgorithmSlidingWindow keeps a log of the request times in the last window so the limit
+	// holds for any window, at the cost of storing one entry per request.
+	AlgorithmSlidingWindow = "sliding-window"
+	// AlgorithmTokenBucket refills the budget continuously and allows short bursts up to the limit.
+	AlgorithmTokenBucket = "token-bucket"
+)
+
 type Config struct {
+	// The budget of the default policy
 	RequestsPerTimeFrame int
 	TimeFrame            time.Duration
 	Enabled              bool
+	Backend              string
+	Algorithm            string
+	// Policies are the budgets of the route groups that differ from the default policy
+	Policies map[string]Policy
+	// AdminMultiplier scales every budget for clients in the admin tier
+	AdminMultiplier int
+	// AdminLevel is the minimum role level of the admin tier
+	AdminLevel int
+}
+
+// Policy is a named budget that a group of routes is limited by
+type Policy struct {
+	RequestsPerTimeFrame int
+	TimeFrame            time.Duration
+}
+
+// New creates the limiter for the configured algorithm and backend. The redis client is only
+// used, and must not be nil, with the redis backend.
+func New(cfg Config, rds *redis.Client) (Limiter, error) {
+	limit, window := cfg.RequestsPerTimeFrame, cfg.TimeFrame
+
+	switch cfg.Backend {
+	case BackendMemory:
+		switch cfg.Algorithm {
+		case AlgorithmFixedWindow:
+			return NewFixedWindowLimiter(limit, window), nil
+		case AlgorithmSlidingWindow:
+			return NewSlidingWindowLimiter(limit, window), nil
+		case AlgorithmTokenBucket:
+			return NewTokenBucketLimiter(limit, window), nil
+		}
+	case BackendRedis:
+		if rds == nil {
+			return nil, fmt.Errorf("the %s rate limiter backend requires a redis client", cfg.Backend)
+		}
+		switch cfg.Algorithm {
+		case AlgorithmFixedWindow:
+			return NewRedisFixedWindowLimiter(rds, limit, window), nil
+		case AlgorithmSlidingWindow:
+			return NewRedisSlidingWindowLimiter(rds, limit, window), nil
+		case AlgorithmTokenBucket:
+			return NewRedisTokenBucketLimiter(rds, limit, window), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown rate limiter backend: %s", cfg.Backend)
+	}
+
+	return nil, fmt.Errorf("unknown rate limiter algorithm: %s", cfg.Algorithm)
 }
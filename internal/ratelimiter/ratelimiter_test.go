@@ -0,0 +1,245 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for the limiters that read the time through now
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Unix(1_700_000_000, 0)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// set makes the limiter use the clock, limiters without a clock are returned unchanged
+func (c *fakeClock) set(l Limiter) Limiter {
+	switch rl := l.(type) {
+	case *SlidingWindowLimiter:
+		rl.now = c.now
+	case *TokenBucketLimiter:
+		rl.now = c.now
+	case *RedisSlidingWindowLimiter:
+		rl.now = c.now
+	case *RedisTokenBucketLimiter:
+		rl.now = c.now
+	}
+	return l
+}
+
+func TestLimiters(t *testing.T) {
+	limit := 5
+	window := time.Second * 10
+
+	_, rds := newTestRedis(t)
+
+	// The windows restart with the first request and the sliding windows with the last one, a
+	// bucket refills the tokens of every request. The time does not advance between requests.
+	fullWindow := func(requests int) time.Duration { return window }
+	refill := func(requests int) time.Duration {
+		return window * time.Duration(requests) / time.Duration(limit)
+	}
+
+	limiters := map[string]struct {
+		newLimiter func() Limiter
+		// resetAfter is the expected ResetAfter after the requests, nil for the limiters on the
+		// wall clock which only reset within the window
+		resetAfter func(requests int) time.Duration
+	}{
+		"fixed window": {
+			func() Limiter { return NewFixedWindowLimiter(limit, window) }, nil,
+		},
+		"sliding window": {
+			func() Limiter { return NewSlidingWindowLimiter(limit, window) }, fullWindow,
+		},
+		"token bucket": {
+			func() Limiter { return NewTokenBucketLimiter(limit, window) }, refill,
+		},
+		"redis fixed window": {
+			func() Limiter { return NewRedisFixedWindowLimiter(rds, limit, window) }, fullWindow,
+		},
+		"redis sliding window": {
+			func() Limiter { return NewRedisSlidingWindowLimiter(rds, limit, window) }, fullWindow,
+		},
+		"redis token bucket": {
+			func() Limiter { return NewRedisTokenBucketLimiter(rds, limit, window) }, refill,
+		},
+	}
+
+	for name, tc := range limiters {
+		t.Run(name+" should allow requests up to the limit", func(t *testing.T) {
+			rl := newFakeClock().set(tc.newLimiter())
+			ip := "10.0.1.1-" + name
+
+			for i := range limit {
+				d := rl.Allow(ip)
+				if !d.Allowed {
+					t.Fatalf("request %d should be allowed", i+1)
+				}
+				if d.Limit != limit || d.Remaining != limit-i-1 {
+					t.Errorf("expected %d/%d remaining; got %d/%d", limit-i-1, limit, d.Remaining, d.Limit)
+				}
+				if tc.resetAfter == nil {
+					if d.ResetAfter <= 0 || d.ResetAfter > window {
+						t.Errorf("expected reset within the window; got %v", d.ResetAfter)
+					}
+				} else if want := tc.resetAfter(i + 1); d.ResetAfter != want {
+					t.Errorf("request %d: expected reset after %v; got %v", i+1, want, d.ResetAfter)
+				}
+			}
+
+			d := rl.Allow(ip)
+			if d.Allowed {
+				t.Fatal("request over the limit should not be allowed")
+			}
+			if d.Remaining != 0 {
+				t.Errorf("expected no remaining requests; got %d", d.Remaining)
+			}
+			if d.RetryAfter <= 0 || d.RetryAfter > window {
+				t.Errorf("expected retry after within the window; got %v", d.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowAcrossBoundary(t *testing.T) {
+	limit := 5
+	window := time.Second * 10
+
+	_, rds := newTestRedis(t)
+
+	for name, rl := range map[string]Limiter{
+		"memory": NewSlidingWindowLimiter(limit, window),
+		"redis":  NewRedisSlidingWindowLimiter(rds, limit, window),
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			clock.set(rl)
+
+			// Use the whole budget at the end of a window...
+			clock.advance(window * 8 / 10)
+			for range limit {
+				clock.advance(window / 50)
+				d := rl.Allow("10.0.2.1")
+				if !d.Allowed {
+					t.Fatal("request should be allowed")
+				}
+				// The budget is restored once the latest request leaves the window
+				if d.ResetAfter != window {
+					t.Errorf("expected reset after %v; got %v", window, d.ResetAfter)
+				}
+			}
+
+			// ...a fixed window would start over here and allow twice the limit
+			clock.advance(window * 2 / 10)
+			if d := rl.Allow("10.0.2.1"); d.Allowed {
+				t.Fatal("request should not be allowed until the first requests leave the window")
+			}
+
+			clock.advance(window * 9 / 10)
+			if d := rl.Allow("10.0.2.1"); !d.Allowed {
+				t.Fatal("request should be allowed once the first requests left the window")
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	limit := 5
+	window := time.Second * 10
+
+	_, rds := newTestRedis(t)
+
+	for name, rl := range map[string]Limiter{
+		"memory": NewTokenBucketLimiter(limit, window),
+		"redis":  NewRedisTokenBucketLimiter(rds, limit, window),
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			clock.set(rl)
+
+			for range limit {
+				rl.Allow("10.0.3.1")
+			}
+
+			d := rl.Allow("10.0.3.1")
+			if d.Allowed {
+				t.Fatal("request should not be allowed with an empty bucket")
+			}
+			if d.RetryAfter != window/time.Duration(limit) {
+				t.Errorf("expected to retry after one token is refilled; got %v", d.RetryAfter)
+			}
+
+			clock.advance(d.RetryAfter)
+			if d := rl.Allow("10.0.3.1"); !d.Allowed {
+				t.Fatal("request should be allowed after a token is refilled")
+			}
+			if d := rl.Allow("10.0.3.1"); d.Allowed {
+				t.Fatal("only one token should have been refilled")
+			}
+		})
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	cfg := Config{
+		RequestsPerTimeFrame: 2,
+		TimeFrame:            time.Minute,
+		Backend:              BackendMemory,
+		Algorithm:            AlgorithmFixedWindow,
+		Policies: map[string]Policy{
+			"ops": {RequestsPerTimeFrame: 4, TimeFrame: time.Minute},
+		},
+		AdminMultiplier: 3,
+	}
+
+	policies, err := NewPolicies(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  string
+		tier    Tier
+		allowed int
+	}{
+		{"default policy", DefaultPolicy, TierUser, 2},
+		{"unknown policy uses the default budget", "unknown", TierUser, 2},
+		{"declared policy", "ops", TierUser, 4},
+		{"admin tier", "ops", TierAdmin, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "client-" + tt.name
+			for i := range tt.allowed {
+				if d := policies.Allow(tt.policy, tt.tier, key); !d.Allowed {
+					t.Fatalf("request %d should be allowed", i+1)
+				}
+			}
+			if d := policies.Allow(tt.policy, tt.tier, key); d.Allowed {
+				t.Fatalf("request %d should not be allowed", tt.allowed+1)
+			}
+		})
+	}
+
+	t.Run("policies do not share budgets", func(t *testing.T) {
+		for range 2 {
+			policies.Allow(DefaultPolicy, TierUser, "shared")
+		}
+		if d := policies.Allow("ops", TierUser, "shared"); !d.Allowed {
+			t.Fatal("the ops budget should not be used by the default policy")
+		}
+	})
+}
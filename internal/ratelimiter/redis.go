@@ -0,0 +1,225 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisTimeout = 100 * time.Millisecond
+
+// NOTE: Each algorithm runs as a script so that reading and updating the state of a client is
+// atomic across all API replicas. Times are passed in milliseconds by the caller.
+
+// fixedWindowScript increments the counter of the client and starts the window on the first
+// request.
+var fixedWindowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// slidingWindowScript keeps the request times of the client in a sorted set.
+// ARGV: now, window, limit, unique member for this request
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < tonumber(ARGV[3]) then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+return {allowed, count, oldest[2] or ARGV[1], newest[2] or ARGV[1]}
+`)
+
+// tokenBucketScript refills the bucket of the client for the time elapsed since its last
+// request and takes a token if there is one.
+// ARGV: now, window, limit
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or limit
+local last = tonumber(state[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - last) * limit / window)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", ARGV[1])
+redis.call("PEXPIRE", KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisFixedWindowLimiter is a fixed window limiter whose counters live in Redis so that every
+// API replica shares the same budget per client.
+type RedisFixedWindowLimiter struct {
+	rds    *redis.Client
+	limit  int
+	window time.Duration
+}
+
+func NewRedisFixedWindowLimiter(rds *redis.Client, limit int, window time.Duration) Limiter {
+	return &RedisFixedWindowLimiter{
+		rds:    rds,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// NOTE: If Redis is unavailable the request is allowed. We would rather serve requests without
+// rate limiting than take the whole API down with the cache. This applies to all Redis limiters.
+func (rl *RedisFixedWindowLimiter) Allow(ip string) Decision {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	key := fmt.Sprintf("ratelimit-%s", ip)
+	res, err := fixedWindowScript.Run(ctx, rl.rds, []string{key}, rl.window.Milliseconds()).Slice()
+	if err != nil || len(res) != 2 {
+		return failOpen(rl.limit)
+	}
+
+	count, _ := res[0].(int64)
+	ttl, _ := res[1].(int64)
+
+	resetAfter := rl.window
+	if ttl >= 0 {
+		resetAfter = time.Duration(ttl) * time.Millisecond
+	}
+
+	if count <= int64(rl.limit) {
+		return Decision{
+			Allowed:    true,
+			Limit:      rl.limit,
+			Remaining:  rl.limit - int(count),
+			ResetAfter: resetAfter,
+		}
+	}
+
+	return Decision{
+		Allowed:    false,
+		Limit:      rl.limit,
+		Remaining:  0,
+		ResetAfter: resetAfter,
+		RetryAfter: resetAfter,
+	}
+}
+
+// RedisSlidingWindowLimiter is the Redis version of SlidingWindowLimiter.
+type RedisSlidingWindowLimiter struct {
+	rds    *redis.Client
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewRedisSlidingWindowLimiter(rds *redis.Client, limit int, window time.Duration) Limiter {
+	return &RedisSlidingWindowLimiter{
+		rds:    rds,
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+func (rl *RedisSlidingWindowLimiter) Allow(ip string) Decision {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	now := rl.now().UnixMilli()
+	key := fmt.Sprintf("ratelimit-sw-%s", ip)
+	// Requests in the same millisecond need distinct members in the sorted set
+	member := fmt.Sprintf("%d-%d", now, rand.Int64())
+
+	res, err := slidingWindowScript.Run(
+		ctx, rl.rds, []string{key}, now, rl.window.Milliseconds(), rl.limit, member,
+	).Slice()
+	if err != nil || len(res) != 4 {
+		return failOpen(rl.limit)
+	}
+
+	allowed, _ := res[0].(int64)
+	count, _ := res[1].(int64)
+	oldest := parseMillis(res[2])
+	newest := parseMillis(res[3])
+
+	if allowed == 1 {
+		return Decision{
+			Allowed:    true,
+			Limit:      rl.limit,
+			Remaining:  rl.limit - int(count),
+			ResetAfter: time.Duration(newest+rl.window.Milliseconds()-now) * time.Millisecond,
+		}
+	}
+
+	return Decision{
+		Allowed:    false,
+		Limit:      rl.limit,
+		Remaining:  0,
+		ResetAfter: time.Duration(newest+rl.window.Milliseconds()-now) * time.Millisecond,
+		RetryAfter: time.Duration(oldest+rl.window.Milliseconds()-now) * time.Millisecond,
+	}
+}
+
+// RedisTokenBucketLimiter is the Redis version of TokenBucketLimiter.
+type RedisTokenBucketLimiter struct {
+	rds    *redis.Client
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewRedisTokenBucketLimiter(rds *redis.Client, limit int, window time.Duration) Limiter {
+	return &RedisTokenBucketLimiter{
+		rds:    rds,
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+func (rl *RedisTokenBucketLimiter) Allow(ip string) Decision {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	key := fmt.Sprintf("ratelimit-tb-%s", ip)
+	res, err := tokenBucketScript.Run(
+		ctx, rl.rds, []string{key}, rl.now().UnixMilli(), rl.window.Milliseconds(), rl.limit,
+	).Slice()
+	if err != nil || len(res) != 2 {
+		return failOpen(rl.limit)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensRaw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensRaw, 64)
+	if err != nil {
+		return failOpen(rl.limit)
+	}
+
+	return tokenBucketDecision(allowed == 1, tokens, rl.limit, rl.window)
+}
+
+func failOpen(limit int) Decision {
+	return Decision{Allowed: true, Limit: limit, Remaining: limit}
+}
+
+func parseMillis(value any) int64 {
+	s, _ := value.(string)
+	ms, _ := strconv.ParseFloat(s, 64)
+	return int64(ms)
+}
@@ -0,0 +1,71 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rds.Close() })
+
+	return mr, rds
+}
+
+func TestRedisFixedWindowLimiter(t *testing.T) {
+	mr, rds := newTestRedis(t)
+
+	limit := 5
+	window := time.Second * 10
+
+	t.Run("should share the budget across limiter instances", func(t *testing.T) {
+		replicaA := NewRedisFixedWindowLimiter(rds, limit, window)
+		replicaB := NewRedisFixedWindowLimiter(rds, limit, window)
+
+		for i := range limit {
+			replica := replicaA
+			if i%2 == 1 {
+				replica = replicaB
+			}
+			if d := replica.Allow("10.0.0.2"); !d.Allowed {
+				t.Fatalf("request %d should be allowed", i+1)
+			}
+		}
+
+		if d := replicaB.Allow("10.0.0.2"); d.Allowed {
+			t.Fatal("request over the shared limit should not be allowed")
+		}
+	})
+
+	t.Run("should reset after the window", func(t *testing.T) {
+		rl := NewRedisFixedWindowLimiter(rds, 1, window)
+		if d := rl.Allow("10.0.0.3"); !d.Allowed {
+			t.Fatal("first request should be allowed")
+		}
+		if d := rl.Allow("10.0.0.3"); d.Allowed {
+			t.Fatal("second request should not be allowed")
+		}
+
+		mr.FastForward(window)
+
+		if d := rl.Allow("10.0.0.3"); !d.Allowed {
+			t.Fatal("request in a new window should be allowed")
+		}
+	})
+
+	t.Run("should allow requests when redis is down", func(t *testing.T) {
+		mr, rds := newTestRedis(t)
+		rl := NewRedisFixedWindowLimiter(rds, 1, window)
+		mr.Close()
+
+		if d := rl.Allow("10.0.0.4"); !d.Allowed {
+			t.Fatal("request should be allowed when redis is unavailable")
+		}
+	})
+}
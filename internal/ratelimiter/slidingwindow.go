@@ -0,0 +1,83 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindowLimiter keeps a log of the request times of every client. A request is allowed
+// if fewer than limit requests were made in the window that ends now, so unlike the fixed window
+// a client can never exceed the limit across a window boundary.
+type SlidingWindowLimiter struct {
+	sync.Mutex
+	clients   map[string][]time.Time
+	limit     int
+	window    time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewSlidingWindowLimiter(limit int, window time.Duration) Limiter {
+	return &SlidingWindowLimiter{
+		clients: make(map[string][]time.Time),
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+func (rl *SlidingWindowLimiter) Allow(ip string) Decision {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	log := prune(rl.clients[ip], now.Add(-rl.window))
+
+	if len(log) < rl.limit {
+		log = append(log, now)
+		rl.clients[ip] = log
+		return Decision{
+			Allowed:    true,
+			Limit:      rl.limit,
+			Remaining:  rl.limit - len(log),
+			ResetAfter: log[len(log)-1].Add(rl.window).Sub(now),
+		}
+	}
+
+	rl.clients[ip] = log
+	// The next request is allowed once the oldest request leaves the window
+	retryAfter := log[0].Add(rl.window).Sub(now)
+	return Decision{
+		Allowed:    false,
+		Limit:      rl.limit,
+		Remaining:  0,
+		ResetAfter: log[len(log)-1].Add(rl.window).Sub(now),
+		RetryAfter: retryAfter,
+	}
+}
+
+// sweep drops the clients without requests in the last window so the map does not grow with
+// every client ever seen. It runs at most once per window.
+func (rl *SlidingWindowLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+	rl.lastSweep = now
+
+	for ip, log := range rl.clients {
+		if len(prune(log, now.Add(-rl.window))) == 0 {
+			delete(rl.clients, ip)
+		}
+	}
+}
+
+// prune removes the request times at or before start from the log, which is sorted by time.
+func prune(log []time.Time, start time.Time) []time.Time {
+	ix := 0
+	for ix < len(log) && !log[ix].After(start) {
+		ix++
+	}
+	return log[ix:]
+}
@@ -0,0 +1,100 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucketLimiter gives every client a bucket of limit tokens that refills continuously at
+// limit tokens per window. Each request takes a token, so clients can burst up to the limit and
+// then make requests at the refill rate.
+type TokenBucketLimiter struct {
+	sync.Mutex
+	clients   map[string]*tokenBucket
+	limit     int
+	window    time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucketLimiter(limit int, window time.Duration) Limiter {
+	return &TokenBucketLimiter{
+		clients: make(map[string]*tokenBucket),
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+func (rl *TokenBucketLimiter) Allow(ip string) Decision {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	bucket, exists := rl.clients[ip]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(rl.limit), last: now}
+		rl.clients[ip] = bucket
+	}
+
+	bucket.tokens = rl.refill(bucket, now)
+	bucket.last = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return tokenBucketDecision(allowed, bucket.tokens, rl.limit, rl.window)
+}
+
+func (rl *TokenBucketLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.last)
+	added := float64(rl.limit) * float64(elapsed) / float64(rl.window)
+	return math.Min(float64(rl.limit), bucket.tokens+added)
+}
+
+// tokenBucketDecision describes a bucket holding tokens after the request was counted.
+func tokenBucketDecision(allowed bool, tokens float64, limit int, window time.Duration) Decision {
+	// timeToRefill returns how long it takes to add the given number of tokens to a bucket
+	timeToRefill := func(tokens float64) time.Duration {
+		return time.Duration(math.Ceil(tokens * float64(window) / float64(limit)))
+	}
+
+	d := Decision{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: timeToRefill(float64(limit) - tokens),
+	}
+
+	if !allowed {
+		d.Remaining = 0
+		d.RetryAfter = timeToRefill(1 - tokens)
+	}
+
+	return d
+}
+
+// sweep drops the buckets that are full again, a new bucket is full so forgetting them does not
+// change any decision. It runs at most once per window.
+func (rl *TokenBucketLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+	rl.lastSweep = now
+
+	for ip, bucket := range rl.clients {
+		if rl.refill(bucket, now) >= float64(rl.limit) {
+			delete(rl.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Audited actions, named <target>.<verb>
+const (
+	AuditPostUpdated     = "post.updated"
+	AuditPostDeleted     = "post.deleted"
+	AuditPostHidden      = "post.hidden"
+	AuditPostRestored    = "post.restored"
+	AuditCommentUpdated  = "comment.updated"
+	AuditCommentDeleted  = "comment.deleted"
+	AuditCommentHidden   = "comment.hidden"
+	AuditCommentRestored = "comment.restored"
+	AuditUserRoleChanged = "user.role_changed"
+	AuditUserActivated   = "user.activated"
+	AuditUserDeactivated = "user.deactivated"
+	AuditUserDeleted     = "user.deleted"
+)
+
+// Audited target types
+const (
+	AuditTargetPost    = "post"
+	AuditTargetComment = "comment"
+	AuditTargetUser    = "user"
+)
+
+// AuditEvent is a change made by ActorID to a target, with snapshots of the target before and
+// after the change. Before is empty for creations and After for deletions.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	ActorID    int64           `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+type AuditStore struct {
+	db *pgxpool.Pool
+}
+
+func (s *AuditStore) Record(ctx context.Context, event *AuditEvent) error {
+	query := /* sql */ `
+		INSERT INTO audit_events
+			(actor_id, action, target_type, target_id, request_id, ip, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var createdAt time.Time
+	if err := s.db.QueryRow(
+		ctx,
+		query,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.RequestID,
+		event.IP,
+		nullableJSON(event.Before),
+		nullableJSON(event.After),
+	).Scan(&event.ID, &createdAt); err != nil {
+		return err
+	}
+
+	event.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+// List returns the events matching aq, newest first
+func (s *AuditStore) List(ctx context.Context, aq PaginatedAuditQuery) ([]AuditEvent, error) {
+	query := /* sql */ `
+		SELECT id, actor_id, action, target_type, target_id, coalesce(request_id, ''), coalesce(ip, ''),
+			before, after, created_at
+		FROM audit_events
+		WHERE ($2::bigint IS NULL OR actor_id = $2)
+			AND ($3 = '' OR action = $3)
+			AND ($4 = '' OR target_type = $4)
+			AND ($5::bigint IS NULL OR target_id = $5)
+			AND ($6::timestamptz IS NULL OR created_at >= $6)
+			AND ($7::timestamptz IS NULL OR created_at < $7)
+			AND ($8::timestamptz IS NULL OR (created_at, id) < ($8, $9::bigint))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if aq.Cursor != nil {
+		cursorCreatedAt = &aq.Cursor.CreatedAt
+		cursorID = aq.Cursor.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(
+		ctx,
+		query,
+		aq.Limit,
+		aq.ActorID,
+		aq.Action,
+		aq.TargetType,
+		aq.TargetID,
+		aq.Since,
+		aq.Until,
+		cursorCreatedAt,
+		cursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		var e AuditEvent
+		var createdAt time.Time
+		if err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.RequestID,
+			&e.IP,
+			&e.Before,
+			&e.After,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// nullableJSON stores empty snapshots as NULL instead of invalid json
+func nullableJSON(data json.RawMessage) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return []byte(data)
+}
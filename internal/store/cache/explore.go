@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/atomicmeganerd/gopher-social/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+type ExploreStore struct {
+	rds *redis.Client
+}
+
+const exploreKey = "explore-trending"
+
+// Get returns the precomputed trending posts, or nil if they are not cached.
+func (e *ExploreStore) Get(ctx context.Context) ([]store.TrendingPost, error) {
+	if e.rds == nil {
+		return nil, nil
+	}
+
+	data, err := e.rds.Get(ctx, exploreKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []store.TrendingPost
+	if err := json.Unmarshal([]byte(data), &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Set replaces the trending posts. They expire so that a stale ranking is not served forever if
+// the instances refreshing it stop.
+func (e *ExploreStore) Set(
+	ctx context.Context, posts []store.TrendingPost, expiry time.Duration,
+) error {
+	if posts == nil {
+		return errors.New("posts cannot be nil")
+	}
+
+	if e.rds == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(posts)
+	if err != nil {
+		return err
+	}
+
+	return e.rds.Set(ctx, exploreKey, data, expiry).Err()
+}
+
+// Delete evicts the trending posts, the next Get misses and they are ranked again.
+func (e *ExploreStore) Delete(ctx context.Context) error {
+	if e.rds == nil {
+		return nil
+	}
+
+	return e.rds.Del(ctx, exploreKey).Err()
+}
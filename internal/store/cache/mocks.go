@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/stretchr/testify/mock"
@@ -9,7 +10,8 @@ import (
 
 func NewMockStore() *Storage {
 	return &Storage{
-		Users: &MockUsersCacheStorage{},
+		Users:   &MockUsersCacheStorage{},
+		Explore: &MockExploreCacheStorage{},
 	}
 }
 
@@ -27,3 +29,30 @@ func (m *MockUsersCacheStorage) Set(ctx context.Context, user *store.User) error
 	// We set this to 0 because the user argument will be nil here in the test
 	return args.Error(0)
 }
+
+func (m *MockUsersCacheStorage) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockExploreCacheStorage struct {
+	mock.Mock
+}
+
+func (m *MockExploreCacheStorage) Get(ctx context.Context) ([]store.TrendingPost, error) {
+	args := m.Called()
+	posts, _ := args.Get(0).([]store.TrendingPost)
+	return posts, args.Error(1)
+}
+
+func (m *MockExploreCacheStorage) Set(
+	ctx context.Context, posts []store.TrendingPost, expiry time.Duration,
+) error {
+	args := m.Called(posts, expiry)
+	return args.Error(0)
+}
+
+func (m *MockExploreCacheStorage) Delete(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
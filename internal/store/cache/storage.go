@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/atomicmeganerd/gopher-social/internal/store"
 	"github.com/redis/go-redis/v9"
@@ -11,6 +12,12 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, int64) error
+	}
+	Explore interface {
+		Get(context.Context) ([]store.TrendingPost, error)
+		Set(context.Context, []store.TrendingPost, time.Duration) error
+		Delete(context.Context) error
 	}
 }
 
@@ -19,5 +26,8 @@ func NewCacheStorage(rds *redis.Client) *Storage {
 		Users: &UserStore{
 			rds: rds,
 		},
+		Explore: &ExploreStore{
+			rds: rds,
+		},
 	}
 }
@@ -68,3 +68,14 @@ func (u *UserStore) Set(ctx context.Context, user *store.User) error {
 
 	return nil
 }
+
+// Delete evicts the user, the next Get misses and the user is loaded from the database again.
+func (u *UserStore) Delete(ctx context.Context, userID int64) error {
+	if u.rds == nil {
+		return nil
+	}
+
+	cacheKey := fmt.Sprintf("user-%v", userID)
+
+	return u.rds.Del(ctx, cacheKey).Err()
+}
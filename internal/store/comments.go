@@ -4,86 +4,334 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID         int64     `json:"id"`
+	PostID     int64     `json:"post_id"`
+	UserID     int64     `json:"user_id"`
+	ParentID   *int64    `json:"parent_id"`
+	Content    string    `json:"content"`
+	Entities   []Entity  `json:"entities"`
+	CreatedAt  string    `json:"created_at"`
+	UpdatedAt  string    `json:"updated_at"`
+	Version    int       `json:"version"`
+	User       User      `json:"user"`
+	ReplyCount int       `json:"reply_count"`
+	Replies    []Comment `json:"replies,omitempty"`
 }
 
 type CommentStore struct {
 	db *pgxpool.Pool
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
-	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, u.username, u.id FROM comments c
+// GetByPostID returns the comment threads of a post as a tree. The top level (the comments of
+// the post, or the replies of cq.ParentID) is paginated with limit/offset. Every deeper level
+// holds at most cq.Limit replies per comment and stops at cq.Depth levels; ReplyCount tells the
+// client when there are more replies to fetch for a comment. Only the replies of the returned
+// page are walked.
+func (s *CommentStore) GetByPostID(
+	ctx context.Context, postID int64, cq PaginatedCommentQuery,
+) ([]Comment, error) {
+	query := /* sql */ `
+		WITH RECURSIVE thread AS (
+			(
+				SELECT c.id, 1 AS depth
+				FROM comments c
+				WHERE c.post_id = $1 AND c.parent_id IS NOT DISTINCT FROM $2
+					AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+				ORDER BY c.created_at ASC, c.id ASC
+				LIMIT $4 OFFSET $5
+			)
+			UNION ALL
+			SELECT r.id, t.depth + 1
+			FROM thread t
+			CROSS JOIN LATERAL (
+				SELECT c.id
+				FROM comments c
+				WHERE c.post_id = $1 AND c.parent_id = t.id
+					AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+				ORDER BY c.created_at ASC, c.id ASC
+				LIMIT $4
+			) r
+			WHERE t.depth < $3
+		)
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.content, c.entities, c.created_at,
+			c.updated_at, c.version, u.username, u.id, t.depth, (
+				SELECT COUNT(*) FROM comments rc
+				WHERE rc.parent_id = c.id AND rc.hidden_at IS NULL AND rc.deleted_at IS NULL
+			) AS reply_count
+		FROM thread t
+		JOIN comments c ON c.id = t.id
 		JOIN users u ON c.user_id = u.id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at ASC
+		ORDER BY t.depth ASC, c.created_at ASC, c.id ASC
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.Query(ctx, query, postID)
+	rows, err := s.db.Query(ctx, query, postID, cq.ParentID, cq.Depth, cq.Limit, cq.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	comments := []Comment{}
+	depths := []int{}
 	for rows.Next() {
 		var c Comment
 		c.User = User{}
-		var createdAt time.Time
+		var createdAt, updatedAt time.Time
+		var depth int
 		if err := rows.Scan(
 			&c.ID,
 			&c.PostID,
 			&c.UserID,
+			&c.ParentID,
 			&c.Content,
+			&c.Entities,
 			&createdAt,
+			&updatedAt,
+			&c.Version,
 			&c.User.Username,
 			&c.User.ID,
+			&depth,
+			&c.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
 		c.CreatedAt = createdAt.Format(time.RFC3339)
+		c.UpdatedAt = updatedAt.Format(time.RFC3339)
 		comments = append(comments, c)
+		depths = append(depths, depth)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return comments, nil
+	return buildCommentTree(comments, depths), nil
 }
 
-func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
-	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+// buildCommentTree nests the replies under their parents. The rows are ordered by depth so the
+// top level comes first.
+func buildCommentTree(comments []Comment, depths []int) []Comment {
+	replies := make(map[int64][]Comment)
+	roots := []Comment{}
+	for ix, c := range comments {
+		if depths[ix] == 1 {
+			roots = append(roots, c)
+			continue
+		}
+		replies[*c.ParentID] = append(replies[*c.ParentID], c)
+	}
+
+	var attach func(c *Comment)
+	attach = func(c *Comment) {
+		c.Replies = replies[c.ID]
+		for ix := range c.Replies {
+			attach(&c.Replies[ix])
+		}
+	}
+
+	for ix := range roots {
+		attach(&roots[ix])
+	}
+
+	return roots
+}
+
+func (s *CommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	query := /* sql */ `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.content, c.entities, c.created_at,
+			c.updated_at, c.version, u.username, u.id
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = $1 AND c.hidden_at IS NULL AND c.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var createdAt time.Time
-	err := s.db.QueryRow(ctx, query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-	).Scan(&comment.ID, &createdAt)
+	c := &Comment{}
+	var createdAt, updatedAt time.Time
+	if err := s.db.QueryRow(ctx, query, commentID).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.UserID,
+		&c.ParentID,
+		&c.Content,
+		&c.Entities,
+		&createdAt,
+		&updatedAt,
+		&c.Version,
+		&c.User.Username,
+		&c.User.ID,
+	); err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	c.CreatedAt = createdAt.Format(time.RFC3339)
+	c.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return c, nil
+}
+
+// Create inserts the comment and records the users it mentions, enqueuing the emails of
+// mentionEmail for them.
+func (s *CommentStore) Create(
+	ctx context.Context, comment *Comment, mentionEmail MentionEmailFunc,
+) error {
+	query := `
+		INSERT INTO comments (post_id, user_id, parent_id, content, entities)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at, version
+	`
+
+	if comment.Entities == nil {
+		comment.Entities = []Entity{}
+	}
+
+	var createdAt, updatedAt time.Time
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(queryCtx, query,
+			comment.PostID,
+			comment.UserID,
+			comment.ParentID,
+			comment.Content,
+			comment.Entities,
+		).Scan(&comment.ID, &createdAt, &updatedAt, &comment.Version); err != nil {
+			return err
+		}
+
+		return setMentions(
+			ctx, tx, "comment_id", comment.ID, comment.PostID, comment.Entities, mentionEmail,
+		)
+	})
 	if err != nil {
 		return err
 	}
 	comment.CreatedAt = createdAt.Format(time.RFC3339)
+	comment.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return nil
+}
+
+// Update saves the content of the comment if it was not changed since it was read, otherwise
+// the comment is not found.
+func (s *CommentStore) Update(
+	ctx context.Context, comment *Comment, mentionEmail MentionEmailFunc,
+) error {
+	query := /* sql */ `
+		UPDATE comments
+		SET content = $1, entities = $2, updated_at = now(), version = version + 1
+		WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+		RETURNING updated_at, version
+	`
+
+	if comment.Entities == nil {
+		comment.Entities = []Entity{}
+	}
+
+	var updatedAt time.Time
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(
+			queryCtx,
+			query,
+			comment.Content,
+			comment.Entities,
+			comment.ID,
+			comment.Version,
+		).Scan(&updatedAt, &comment.Version); err != nil {
+			switch err {
+			case pgx.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		return setMentions(
+			ctx, tx, "comment_id", comment.ID, comment.PostID, comment.Entities, mentionEmail,
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	comment.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return nil
+}
+
+// Delete marks the comment as deleted by the user, its replies are no longer shown either. It can
+// be restored until it is purged.
+func (s *CommentStore) Delete(ctx context.Context, commentID, deletedBy int64) error {
+	query := /* sql */ `
+		UPDATE comments SET deleted_at = now(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, query, commentID, deletedBy)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
 
 	return nil
 }
+
+// Restore brings back a deleted comment with its replies
+func (s *CommentStore) Restore(ctx context.Context, commentID int64) error {
+	query := /* sql */ `
+		UPDATE comments SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, query, commentID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Purge removes for good the comments deleted before the given time and their replies. It
+// returns the number of comments removed, replies excluded.
+func (s *CommentStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := /* sql */ `DELETE FROM comments WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
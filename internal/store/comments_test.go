@@ -0,0 +1,48 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	parent := func(id int64) *int64 { return &id }
+
+	// The rows come ordered by depth, then by creation within a level
+	comments := []Comment{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3, ParentID: parent(1)},
+		{ID: 4, ParentID: parent(2)},
+		{ID: 5, ParentID: parent(1)},
+		{ID: 6, ParentID: parent(3)},
+		{ID: 7, ParentID: parent(99)},
+	}
+	depths := []int{1, 1, 2, 2, 2, 3, 2}
+
+	tree := buildCommentTree(comments, depths)
+
+	ids := func(comments []Comment) []int64 {
+		ids := []int64{}
+		for _, c := range comments {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	if got := ids(tree); !slices.Equal(got, []int64{1, 2}) {
+		t.Fatalf("expected the top level comments 1 and 2 but got %v", got)
+	}
+	if got := ids(tree[0].Replies); !slices.Equal(got, []int64{3, 5}) {
+		t.Errorf("expected the replies 3 and 5 under comment 1 but got %v", got)
+	}
+	if got := ids(tree[1].Replies); !slices.Equal(got, []int64{4}) {
+		t.Errorf("expected the reply 4 under comment 2 but got %v", got)
+	}
+	if got := ids(tree[0].Replies[0].Replies); !slices.Equal(got, []int64{6}) {
+		t.Errorf("expected the reply 6 under comment 3 but got %v", got)
+	}
+	if len(tree[0].Replies[1].Replies) != 0 {
+		t.Errorf("expected no replies under comment 5 but got %v", tree[0].Replies[1].Replies)
+	}
+}
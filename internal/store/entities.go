@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Entities of the content of posts and comments
+const (
+	EntityMention = "mention"
+	EntityHashtag = "hashtag"
+)
+
+// A mention or hashtag starts after a space or punctuation, so that emails and URL fragments are
+// not matched. Its name can contain hyphens but not end with one.
+var entityPattern = regexp.MustCompile(
+	`(?:^|[^\p{L}\p{N}_@#/&])([@#])([\p{L}\p{N}_]+(?:-[\p{L}\p{N}_]+)*)`,
+)
+
+// Entity is a mention or a hashtag in the content of a post or a comment, so that clients can
+// render it as a link. Start and End are offsets in Unicode code points, End excluded.
+type Entity struct {
+	Type   string `json:"type"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Text   string `json:"text"`
+	UserID int64  `json:"user_id,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+}
+
+// ParseEntities returns the mentions and hashtags of the content in order. The mentions are not
+// resolved, their UserID is zero.
+func ParseEntities(content string) []Entity {
+	entities := []Entity{}
+	for _, match := range entityPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[2], match[5]
+		name := content[match[4]:end]
+
+		entity := Entity{
+			Start: utf8.RuneCountInString(content[:start]),
+			Text:  content[start:end],
+		}
+		entity.End = entity.Start + utf8.RuneCountInString(entity.Text)
+
+		if content[start] == '@' {
+			entity.Type = EntityMention
+		} else {
+			// Hashtags must be valid tags with a letter, #1 is not a hashtag
+			if !IsValidTag(name) || !strings.ContainsFunc(name, unicode.IsLetter) {
+				continue
+			}
+			entity.Type = EntityHashtag
+			entity.Tag = NormalizeTag(name)
+		}
+
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+// MentionedUsernames returns the usernames mentioned in the entities, without duplicates
+func MentionedUsernames(entities []Entity) []string {
+	usernames := []string{}
+	for _, entity := range entities {
+		username := strings.TrimPrefix(entity.Text, "@")
+		if entity.Type == EntityMention && !slices.Contains(usernames, username) {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// MentionedUserIDs returns the IDs of the users mentioned in the resolved entities
+func MentionedUserIDs(entities []Entity) []int64 {
+	ids := []int64{}
+	for _, entity := range entities {
+		if entity.Type == EntityMention && entity.UserID != 0 &&
+			!slices.Contains(ids, entity.UserID) {
+			ids = append(ids, entity.UserID)
+		}
+	}
+	return ids
+}
+
+// Hashtags returns the tags of the hashtags in the entities
+func Hashtags(entities []Entity) []string {
+	tags := []string{}
+	for _, entity := range entities {
+		if entity.Type == EntityHashtag {
+			tags = append(tags, entity.Tag)
+		}
+	}
+	return NormalizeTags(tags)
+}
+
+// MentionEmailFunc builds the email notifying a user of a mention in a post or in one of its
+// comments. It returns nil when the user must not be notified.
+type MentionEmailFunc func(postID, userID int64) (*OutboxEmail, error)
+
+// setMentions records the users mentioned by the post or comment in column, which is post_id or
+// comment_id, and forgets the users no longer mentioned. The users whose mention is recorded now
+// are notified with the emails of mentionEmail in the same transaction, nil notifies no one.
+func setMentions(
+	ctx context.Context,
+	tx pgx.Tx,
+	column string,
+	id, postID int64,
+	entities []Entity,
+	mentionEmail MentionEmailFunc,
+) error {
+	userIDs := MentionedUserIDs(entities)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `DELETE FROM mentions WHERE ` + column + ` = $1 AND NOT user_id = ANY($2)`
+	if _, err := tx.Exec(ctx, query, id, userIDs); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO mentions (` + column + `, user_id) SELECT $1, unnest($2::bigint[])
+		ON CONFLICT (` + column + `, user_id) DO NOTHING
+		RETURNING user_id`
+	rows, err := tx.Query(ctx, query, id, userIDs)
+	if err != nil {
+		return err
+	}
+
+	inserted := []int64{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		inserted = append(inserted, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if mentionEmail == nil {
+		return nil
+	}
+
+	for _, userID := range inserted {
+		email, err := mentionEmail(postID, userID)
+		if err != nil {
+			return err
+		}
+		if email == nil {
+			continue
+		}
+
+		if err := enqueueEmail(ctx, tx, email); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	entities := ParseEntities("Héllo @gopher, #Go-Lang! mail@example.com #1 x#y #tag- (@ferris)")
+
+	want := []Entity{
+		{Type: EntityMention, Start: 6, End: 13, Text: "@gopher"},
+		{Type: EntityHashtag, Start: 15, End: 23, Text: "#Go-Lang", Tag: "go-lang"},
+		{Type: EntityHashtag, Start: 49, End: 53, Text: "#tag", Tag: "tag"},
+		{Type: EntityMention, Start: 56, End: 63, Text: "@ferris"},
+	}
+	if !slices.Equal(entities, want) {
+		t.Errorf("expected the entities\n%+v\nbut got\n%+v", want, entities)
+	}
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -51,3 +52,102 @@ func (s *FollowerStore) Unfollow(ctx context.Context, userID, followerID int64)
 	_, err := s.db.Exec(ctx, query, userID, followerID)
 	return err
 }
+
+// FollowEntry is a user in a follower or following list
+type FollowEntry struct {
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	FollowedAt string `json:"followed_at"`
+}
+
+// NOTE: A row in followers means that user_id follows follower_id, this is how Follow is called
+// by the API and how the feed joins the table.
+
+// GetFollowers lists the users following userID, most recent first.
+func (s *FollowerStore) GetFollowers(
+	ctx context.Context, userID int64, fq PaginatedFollowQuery,
+) ([]FollowEntry, error) {
+	query := /* sql */ `
+		SELECT u.id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1
+			AND ($3::timestamptz IS NULL OR (f.created_at, u.id) < ($3, $4::bigint))
+		ORDER BY f.created_at DESC, u.id DESC
+		LIMIT $2
+	`
+
+	return s.list(ctx, query, userID, fq)
+}
+
+// GetFollowing lists the users followed by userID, most recent first.
+func (s *FollowerStore) GetFollowing(
+	ctx context.Context, userID int64, fq PaginatedFollowQuery,
+) ([]FollowEntry, error) {
+	query := /* sql */ `
+		SELECT u.id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1
+			AND ($3::timestamptz IS NULL OR (f.created_at, u.id) < ($3, $4::bigint))
+		ORDER BY f.created_at DESC, u.id DESC
+		LIMIT $2
+	`
+
+	return s.list(ctx, query, userID, fq)
+}
+
+func (s *FollowerStore) list(
+	ctx context.Context, query string, userID int64, fq PaginatedFollowQuery,
+) ([]FollowEntry, error) {
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if fq.Cursor != nil {
+		cursorCreatedAt = &fq.Cursor.CreatedAt
+		cursorID = fq.Cursor.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, userID, fq.Limit, cursorCreatedAt, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []FollowEntry{}
+	for rows.Next() {
+		var e FollowEntry
+		var followedAt time.Time
+		if err := rows.Scan(&e.UserID, &e.Username, &followedAt); err != nil {
+			return nil, err
+		}
+		e.FollowedAt = followedAt.Format(time.RFC3339)
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetCounts returns how many users follow userID and how many users userID follows.
+func (s *FollowerStore) GetCounts(
+	ctx context.Context, userID int64,
+) (followers int, following int, err error) {
+	query := /* sql */ `
+		SELECT
+			(SELECT COUNT(*) FROM followers WHERE follower_id = $1),
+			(SELECT COUNT(*) FROM followers WHERE user_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err = s.db.QueryRow(ctx, query, userID).Scan(&followers, &following)
+	return followers, following, err
+}
@@ -2,28 +2,47 @@ package store
 
 import (
 	"context"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
 func NewMockStore() *Storage {
+	outbox := &MockOutboxStore{}
 	return &Storage{
-		Users: &MockUserStore{},
+		Posts:     &MockPostStore{outbox: outbox},
+		Users:     &MockUserStore{outbox: outbox},
+		Comments:  &MockCommentStore{outbox: outbox},
+		Followers: &MockFollowerStore{},
+		Sessions:  &MockSessionStore{},
+		Outbox:    outbox,
+		Roles:     &MockRoleStore{},
+		Reports:   &MockReportStore{},
+		Search:    &MockSearchStore{},
+		Tags:      &MockTagStore{},
+		Reactions: &MockReactionStore{},
+		Audit:     &MockAuditStore{},
 	}
 }
 
+// MockUserStore enqueues the emails of its transactions in the outbox, when it has one
 type MockUserStore struct {
+	outbox *MockOutboxStore
 }
 
 func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	return &User{
-		ID: 42,
+		ID:    42,
+		Email: email,
 	}, nil
 }
 
 func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
-	return nil, nil
+	return &User{
+		ID: userID,
+	}, nil
 }
 
 func (m *MockUserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
@@ -31,12 +50,34 @@ func (m *MockUserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error
 }
 
 func (m *MockUserStore) CreateAndInvite(
-	ctx context.Context, user *User, token string, expiryDuration time.Duration,
+	ctx context.Context,
+	user *User,
+	token string,
+	expiryDuration time.Duration,
+	invitation *OutboxEmail,
 ) error {
 	return nil
 
 }
 
+func (m *MockUserStore) RotateInvitation(
+	ctx context.Context,
+	email, token string,
+	expiryDuration time.Duration,
+	invitation func(*User) (*OutboxEmail, error),
+) error {
+	outboxEmail, err := invitation(&User{
+		ID:       42,
+		Username: "gopher",
+		Email:    email,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.enqueueEmail(ctx, outboxEmail)
+}
+
 func (m *MockUserStore) Activate(ctx context.Context, token string) error {
 	return nil
 }
@@ -44,3 +85,470 @@ func (m *MockUserStore) Activate(ctx context.Context, token string) error {
 func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
 	return nil
 }
+
+func (m *MockUserStore) CreatePasswordReset(
+	ctx context.Context, userID int64, token string, expiry time.Duration, email *OutboxEmail,
+) error {
+	return m.enqueueEmail(ctx, email)
+}
+
+func (m *MockUserStore) enqueueEmail(ctx context.Context, email *OutboxEmail) error {
+	if m.outbox == nil {
+		return nil
+	}
+	return m.outbox.Enqueue(ctx, email)
+}
+
+func (m *MockUserStore) UpdateLocale(ctx context.Context, userID int64, locale string) error {
+	return nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token, newPassword string) error {
+	return nil
+}
+
+// GetByUsernames knows the gopher and ferris users, with the IDs 2 and 3
+func (m *MockUserStore) GetByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	users := []User{}
+	for ix, username := range []string{"gopher", "ferris"} {
+		if slices.Contains(usernames, username) {
+			users = append(users, User{
+				ID:       int64(ix + 2),
+				Username: username,
+				Email:    username + "@example.com",
+				Locale:   DefaultLocale,
+			})
+		}
+	}
+	return users, nil
+}
+
+func (m *MockUserStore) GetAccount(ctx context.Context, userID int64) (*User, error) {
+	return &User{
+		ID:       userID,
+		IsActive: true,
+		Role:     Role{Name: "user", Level: 1},
+	}, nil
+}
+
+func (m *MockUserStore) Search(ctx context.Context, uq PaginatedUserQuery) ([]User, error) {
+	return []User{}, nil
+}
+
+func (m *MockUserStore) SetRole(ctx context.Context, userID, roleID int64) error {
+	return nil
+}
+
+func (m *MockUserStore) Deactivate(ctx context.Context, userID, adminID int64) error {
+	return nil
+}
+
+func (m *MockUserStore) Reactivate(ctx context.Context, userID int64) error {
+	return nil
+}
+
+type MockFollowerStore struct {
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, userID, followerID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, userID, followerID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowers(
+	ctx context.Context, userID int64, fq PaginatedFollowQuery,
+) ([]FollowEntry, error) {
+	return []FollowEntry{}, nil
+}
+
+func (m *MockFollowerStore) GetFollowing(
+	ctx context.Context, userID int64, fq PaginatedFollowQuery,
+) ([]FollowEntry, error) {
+	return []FollowEntry{}, nil
+}
+
+func (m *MockFollowerStore) GetCounts(ctx context.Context, userID int64) (int, int, error) {
+	return 0, 0, nil
+}
+
+type MockSessionStore struct {
+}
+
+func (m *MockSessionStore) Create(
+	ctx context.Context, session *Session, refreshToken string, expiry time.Duration,
+) error {
+	return nil
+}
+
+func (m *MockSessionStore) Rotate(
+	ctx context.Context, refreshToken, newToken string,
+) (*Session, error) {
+	return &Session{ID: "test-session", UserID: 1}, nil
+}
+
+func (m *MockSessionStore) Revoke(ctx context.Context, sessionID string) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeToken(ctx context.Context, jti string, expiry time.Time) error {
+	return nil
+}
+
+func (m *MockSessionStore) IsRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	return false, nil
+}
+
+// MockOutboxStore keeps the enqueued emails in memory
+type MockOutboxStore struct {
+	mu       sync.Mutex
+	Enqueued []OutboxEmail
+}
+
+func (m *MockOutboxStore) Enqueue(ctx context.Context, email *OutboxEmail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Enqueued = append(m.Enqueued, *email)
+	return nil
+}
+
+// enqueueMentions enqueues the mention emails as if every mentioned user was newly mentioned
+func (m *MockOutboxStore) enqueueMentions(
+	ctx context.Context, postID int64, entities []Entity, mentionEmail MentionEmailFunc,
+) error {
+	if m == nil || mentionEmail == nil {
+		return nil
+	}
+
+	for _, userID := range MentionedUserIDs(entities) {
+		email, err := mentionEmail(postID, userID)
+		if err != nil {
+			return err
+		}
+		if email == nil {
+			continue
+		}
+
+		if err := m.Enqueue(ctx, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockOutboxStore) Claim(
+	ctx context.Context, limit int, lease time.Duration,
+) ([]OutboxEmail, error) {
+	return []OutboxEmail{}, nil
+}
+
+func (m *MockOutboxStore) MarkSent(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockOutboxStore) MarkFailed(
+	ctx context.Context, id int64, nextAttempt time.Time, lastErr string,
+) error {
+	return nil
+}
+
+func (m *MockOutboxStore) MarkDead(ctx context.Context, id int64, lastErr string) error {
+	return nil
+}
+
+// MockRoleStore knows the roles created by the migrations
+type MockRoleStore struct {
+}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, roleName string) (*Role, error) {
+	levels := map[string]int{"user": 1, "moderator": 2, "admin": 3}
+
+	level, ok := levels[roleName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Role{ID: int64(level), Name: roleName, Level: level}, nil
+}
+
+// MockAuditStore keeps the recorded events in memory
+type MockAuditStore struct {
+	mu     sync.Mutex
+	Events []AuditEvent
+}
+
+func (m *MockAuditStore) Record(ctx context.Context, event *AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Events = append(m.Events, *event)
+	return nil
+}
+
+func (m *MockAuditStore) List(ctx context.Context, aq PaginatedAuditQuery) ([]AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []AuditEvent{}
+	for _, e := range m.Events {
+		if aq.Action == "" || e.Action == aq.Action {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// MockReportStore files every report with the ID 7
+type MockReportStore struct {
+}
+
+func (m *MockReportStore) Create(ctx context.Context, report *Report) error {
+	report.ID = 7
+	report.Status = ReportStatusOpen
+	report.CreatedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MockReportStore) GetByID(ctx context.Context, reportID int64) (*Report, error) {
+	return &Report{ID: reportID, Status: ReportStatusOpen}, nil
+}
+
+func (m *MockReportStore) List(ctx context.Context, rq PaginatedReportQuery) ([]Report, error) {
+	return []Report{}, nil
+}
+
+func (m *MockReportStore) Claim(ctx context.Context, reportID, moderatorID int64) (*Report, error) {
+	return &Report{ID: reportID, Status: ReportStatusClaimed, ClaimedBy: &moderatorID}, nil
+}
+
+func (m *MockReportStore) Resolve(
+	ctx context.Context, reportID, moderatorID int64, resolution, note string,
+) (*Report, error) {
+	return &Report{ID: reportID, Status: ReportStatusResolved, Resolution: resolution}, nil
+}
+
+// MockPostStore has a single post with ID 1 written by the user with ID 1
+type MockPostStore struct {
+	outbox *MockOutboxStore
+}
+
+func (m *MockPostStore) Create(
+	ctx context.Context, post *Post, mentionEmail MentionEmailFunc,
+) error {
+	post.Tags = NormalizeTags(post.Tags)
+	return m.outbox.enqueueMentions(ctx, post.ID, post.Entities, mentionEmail)
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+	if postID != 1 {
+		return nil, ErrNotFound
+	}
+	return &Post{ID: postID, UserID: 1, Title: "Gophers", Content: "Gophers everywhere"}, nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, postID, deletedBy int64) error {
+	return nil
+}
+
+func (m *MockPostStore) Restore(ctx context.Context, postID int64) error {
+	if postID != 1 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockPostStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockPostStore) Update(
+	ctx context.Context, post *Post, mentionEmail MentionEmailFunc,
+) error {
+	post.Tags = NormalizeTags(post.Tags)
+	return m.outbox.enqueueMentions(ctx, post.ID, post.Entities, mentionEmail)
+}
+
+func (m *MockPostStore) GetUserFeed(
+	ctx context.Context, userID int64, pq PaginatedFeedQuery,
+) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetTrending(
+	ctx context.Context, window time.Duration, limit int,
+) ([]TrendingPost, error) {
+	posts := []TrendingPost{}
+	for ix, tags := range [][]string{{"go"}, {"go", "rust"}, {"rust"}} {
+		post := TrendingPost{Score: float64(3 - ix)}
+		post.ID = int64(ix + 1)
+		post.Title = "Gophers and crabs"
+		post.Tags = tags
+		posts = append(posts, post)
+	}
+	return posts[:min(limit, len(posts))], nil
+}
+
+// MockReactionStore keeps the reactions in memory, one per user and post
+type MockReactionStore struct {
+	mu        sync.Mutex
+	reactions map[[2]int64]ReactionKind
+}
+
+func (m *MockReactionStore) Set(ctx context.Context, reaction *Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reactions == nil {
+		m.reactions = map[[2]int64]ReactionKind{}
+	}
+	m.reactions[[2]int64{reaction.PostID, reaction.UserID}] = reaction.Kind
+	reaction.CreatedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MockReactionStore) Delete(ctx context.Context, reaction *Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := [2]int64{reaction.PostID, reaction.UserID}
+	if kind, ok := m.reactions[key]; !ok || kind != reaction.Kind {
+		return ErrNotFound
+	}
+	delete(m.reactions, key)
+	return nil
+}
+
+func (m *MockReactionStore) GetSummary(
+	ctx context.Context, postID, viewerID int64,
+) (*ReactionSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary := &ReactionSummary{Counts: map[ReactionKind]int{}}
+	for key, kind := range m.reactions {
+		if key[0] != postID {
+			continue
+		}
+		summary.Counts[kind]++
+		summary.Total++
+		if key[1] == viewerID {
+			summary.ViewerReaction = &kind
+		}
+	}
+	return summary, nil
+}
+
+// MockCommentStore has a single comment with ID 1 on the post with ID 1
+type MockCommentStore struct {
+	outbox *MockOutboxStore
+}
+
+func (m *MockCommentStore) Create(
+	ctx context.Context, comment *Comment, mentionEmail MentionEmailFunc,
+) error {
+	return m.outbox.enqueueMentions(ctx, comment.PostID, comment.Entities, mentionEmail)
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	if commentID != 1 {
+		return nil, ErrNotFound
+	}
+	return &Comment{ID: commentID, PostID: 1, UserID: 1, Content: "Nice gophers"}, nil
+}
+
+func (m *MockCommentStore) GetByPostID(
+	ctx context.Context, postID int64, cq PaginatedCommentQuery,
+) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) Update(
+	ctx context.Context, comment *Comment, mentionEmail MentionEmailFunc,
+) error {
+	comment.Version++
+	return m.outbox.enqueueMentions(ctx, comment.PostID, comment.Entities, mentionEmail)
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, commentID, deletedBy int64) error {
+	return nil
+}
+
+func (m *MockCommentStore) Restore(ctx context.Context, commentID int64) error {
+	if commentID != 1 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockCommentStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+// MockSearchStore keeps the last query it ran
+type MockSearchStore struct {
+	mu        sync.Mutex
+	LastQuery PaginatedSearchQuery
+}
+
+func (m *MockSearchStore) Search(
+	ctx context.Context, sq PaginatedSearchQuery,
+) ([]SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.LastQuery = sq
+	return []SearchResult{}, nil
+}
+
+// MockTagStore has the go and rust tags, only go has posts
+type MockTagStore struct {
+}
+
+var mockTags = []Tag{
+	{ID: 1, Name: "go", PostsCount: 1},
+	{ID: 2, Name: "rust"},
+}
+
+func (m *MockTagStore) GetByName(ctx context.Context, name string) (*Tag, error) {
+	for _, tag := range mockTags {
+		if tag.Name == NormalizeTag(name) {
+			return &tag, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockTagStore) GetPosts(
+	ctx context.Context, tagID, viewerID int64, tq PaginatedTagPostsQuery,
+) ([]PostWithMetadata, error) {
+	if tagID != 1 {
+		return []PostWithMetadata{}, nil
+	}
+
+	post := PostWithMetadata{}
+	post.ID = 1
+	post.UserID = 1
+	post.Tags = []string{"go"}
+	return []PostWithMetadata{post}, nil
+}
+
+func (m *MockTagStore) GetTrending(
+	ctx context.Context, window time.Duration, limit int,
+) ([]Tag, error) {
+	return mockTags[:min(limit, len(mockTags))], nil
+}
+
+func (m *MockTagStore) Follow(ctx context.Context, userID, tagID int64) error {
+	return nil
+}
+
+func (m *MockTagStore) Unfollow(ctx context.Context, userID, tagID int64) error {
+	return nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxEmail is an email waiting to be delivered by the outbox worker
+type OutboxEmail struct {
+	ID        int64           `json:"id"`
+	Template  string          `json:"template"`
+	Locale    string          `json:"locale"`
+	Username  string          `json:"username"`
+	Email     string          `json:"email"`
+	Data      json.RawMessage `json:"data"`
+	IsSandbox bool            `json:"is_sandbox"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// NewOutboxEmail encodes the template data of an email so it can be stored in the outbox
+func NewOutboxEmail(
+	template, locale, username, email string, data any, isSandbox bool,
+) (*OutboxEmail, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEmail{
+		Template:  template,
+		Locale:    locale,
+		Username:  username,
+		Email:     email,
+		Data:      encoded,
+		IsSandbox: isSandbox,
+	}, nil
+}
+
+type OutboxStore struct {
+	db *pgxpool.Pool
+}
+
+// Enqueue adds an email to the outbox on its own, prefer enqueueing in the transaction of the
+// change that triggers the email when there is one.
+func (s *OutboxStore) Enqueue(ctx context.Context, email *OutboxEmail) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		return enqueueEmail(ctx, tx, email)
+	})
+}
+
+// Claim returns the pending emails that are due and leases them for the given duration so that
+// other workers skip them. An email whose worker died is picked up again once its lease expires.
+func (s *OutboxStore) Claim(
+	ctx context.Context, limit int, lease time.Duration,
+) ([]OutboxEmail, error) {
+	query := /* sql */ `
+		UPDATE email_outbox
+		SET next_attempt_at = $3
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template, locale, username, email, data, is_sandbox, attempts, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	now := time.Now()
+	rows, err := s.db.Query(ctx, query, now, limit, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []OutboxEmail{}
+	for rows.Next() {
+		var e OutboxEmail
+		var createdAt time.Time
+		if err := rows.Scan(
+			&e.ID,
+			&e.Template,
+			&e.Locale,
+			&e.Username,
+			&e.Email,
+			&e.Data,
+			&e.IsSandbox,
+			&e.Attempts,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		emails = append(emails, e)
+	}
+
+	return emails, rows.Err()
+}
+
+// MarkSent records the delivery of an email. Its data is cleared as it may hold one time tokens.
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+	query := /* sql */ `
+		UPDATE email_outbox
+		SET status = 'sent', sent_at = now(), attempts = attempts + 1, data = NULL
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, id)
+}
+
+// MarkFailed records a failed delivery, the email is tried again at nextAttempt.
+func (s *OutboxStore) MarkFailed(
+	ctx context.Context, id int64, nextAttempt time.Time, lastErr string,
+) error {
+	query := /* sql */ `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, id, nextAttempt, lastErr)
+}
+
+// MarkDead moves an email that keeps failing to the dead letters, it is no longer tried but kept
+// for inspection.
+func (s *OutboxStore) MarkDead(ctx context.Context, id int64, lastErr string) error {
+	query := /* sql */ `
+		UPDATE email_outbox
+		SET status = 'dead', attempts = attempts + 1, last_error = $2
+		WHERE id = $1
+	`
+
+	return s.exec(ctx, query, id, lastErr)
+}
+
+func (s *OutboxStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, args...)
+	return err
+}
+
+func enqueueEmail(ctx context.Context, tx pgx.Tx, email *OutboxEmail) error {
+	query := /* sql */ `
+		INSERT INTO email_outbox (template, locale, username, email, data, is_sandbox)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var createdAt time.Time
+	if err := tx.QueryRow(
+		ctx,
+		query,
+		email.Template,
+		email.Locale,
+		email.Username,
+		email.Email,
+		email.Data,
+		email.IsSandbox,
+	).Scan(
+		&email.ID,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+
+	email.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
@@ -1,20 +1,90 @@
 package store
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type PaginatedFeedQuery struct {
 	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
 	Offset int      `json:"offset" validate:"gte=0"`
 	Sort   string   `json:"sort" validate:"oneof=asc desc"` // ASC or DESC
 	Tags   []string `json:"tags" validate:"max=5"`
 	Search string   `json:"search" validate:"max=100"`
-	Since  string   `json:"since"`
-	Until  string   `json:"until"`
+	// Since and Until bound the creation time of the posts, as RFC3339 or time.DateTime in UTC
+	Since string `json:"since" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00|datetime=2006-01-02 15:04:05"`
+	Until string `json:"until" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00|datetime=2006-01-02 15:04:05"`
+	// Cursor is the opaque keyset position returned as next_cursor by the previous page. When it
+	// is set the offset is ignored.
+	Cursor *Cursor `json:"cursor"`
+}
+
+// Cursor is a keyset position in a list ordered by (created_at, id), so a page can start right
+// after the last row the client saw, even if new rows arrive in the meantime.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL safe string.
+func (c Cursor) Encode() string {
+	// Marshalling a struct of a time and an int cannot fail
+	data, _ := json.Marshal(c) // nolint:errcheck
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// NextFeedCursor returns the cursor for the page after feed, or an empty string if feed is the
+// last page.
+func NextFeedCursor(feed []PostWithMetadata, limit int) (string, error) {
+	if len(feed) == 0 || len(feed) < limit {
+		return "", nil
+	}
+
+	last := feed[len(feed)-1]
+	createdAt, err := time.Parse(time.RFC3339, last.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: last.ID}.Encode(), nil
+}
+
+// NextFollowCursor returns the cursor for the page after entries, or an empty string if entries is the
+// last page.
+func NextFollowCursor(entries []FollowEntry, limit int) (string, error) {
+	if len(entries) == 0 || len(entries) < limit {
+		return "", nil
+	}
+
+	last := entries[len(entries)-1]
+	followedAt, err := time.Parse(time.RFC3339, last.FollowedAt)
+	if err != nil {
+		return "", err
+	}
+
+	return Cursor{CreatedAt: followedAt, ID: last.UserID}.Encode(), nil
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -38,6 +108,16 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		fq.Offset = o
 	}
 
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return fq, err
+		}
+		fq.Cursor = c
+		fq.Offset = 0
+	}
+
 	sort := qs.Get("sort")
 	if sort != "" {
 		fq.Sort = sort
@@ -45,7 +125,7 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	tags := qs.Get("tags")
 	if tags != "" {
-		fq.Tags = strings.Split(tags, ",")
+		fq.Tags = NormalizeTags(strings.Split(tags, ","))
 	}
 
 	search := qs.Get("search")
@@ -55,21 +135,536 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := qs.Get("since")
 	if since != "" {
-		fq.Since = parseTime(since)
+		fq.Since = since
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		fq.Until = parseTime(until)
+		fq.Until = until
 	}
 
 	return fq, nil
 }
 
-func parseTime(value string) string {
-	t, err := time.Parse(time.DateTime, value)
+// TimeRange returns the since and until times of the query, nil when they are not set. The
+// values must have been validated.
+func (fq PaginatedFeedQuery) TimeRange() (since, until *time.Time, err error) {
+	if since, err = parseTime(fq.Since); err != nil {
+		return nil, nil, err
+	}
+
+	if until, err = parseTime(fq.Until); err != nil {
+		return nil, nil, err
+	}
+
+	return since, until, nil
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateTime, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+type PaginatedCommentQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=50"`
+	Offset int `json:"offset" validate:"gte=0"`
+	// Depth is the number of levels of replies returned, 1 means no replies
+	Depth int `json:"depth" validate:"gte=1"`
+	// ParentID selects the replies of a comment instead of the top level comments of the post
+	ParentID *int64 `json:"parent_id"`
+}
+
+func (cq PaginatedCommentQuery) Parse(r *http.Request) (PaginatedCommentQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return cq, err
+		}
+		cq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return cq, err
+		}
+		cq.Offset = o
+	}
+
+	depth := qs.Get("depth")
+	if depth != "" {
+		d, err := strconv.Atoi(depth)
+		if err != nil {
+			return cq, err
+		}
+		cq.Depth = d
+	}
+
+	parentID := qs.Get("parent_id")
+	if parentID != "" {
+		p, err := strconv.ParseInt(parentID, 10, 64)
+		if err != nil {
+			return cq, err
+		}
+		cq.ParentID = &p
+	}
+
+	return cq, nil
+}
+
+type PaginatedFollowQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=50"`
+	Cursor *Cursor `json:"cursor"`
+}
+
+func (fq PaginatedFollowQuery) Parse(r *http.Request) (PaginatedFollowQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return fq, err
+		}
+		fq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return fq, err
+		}
+		fq.Cursor = c
+	}
+
+	return fq, nil
+}
+
+// PaginatedTagPostsQuery pages through the posts of a tag, newest first
+type PaginatedTagPostsQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=20"`
+	Cursor *Cursor `json:"cursor"`
+}
+
+func (tq PaginatedTagPostsQuery) Parse(r *http.Request) (PaginatedTagPostsQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return tq, err
+		}
+		tq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return tq, err
+		}
+		tq.Cursor = c
+	}
+
+	return tq, nil
+}
+
+// TrendingTagQuery limits the trending tags
+type TrendingTagQuery struct {
+	Limit int `json:"limit" validate:"gte=1,lte=50"`
+}
+
+func (tq TrendingTagQuery) Parse(r *http.Request) (TrendingTagQuery, error) {
+	limit := r.URL.Query().Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return tq, err
+		}
+		tq.Limit = l
+	}
+
+	return tq, nil
+}
+
+// PaginatedUserQuery filters the users listed by the admins. Unlike the other lists it includes
+// the inactive users unless Active is set.
+type PaginatedUserQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=50"`
+	Search string  `json:"search" validate:"max=100"`
+	Role   string  `json:"role" validate:"max=255"`
+	Active *bool   `json:"active"`
+	Cursor *Cursor `json:"cursor"`
+}
+
+func (uq PaginatedUserQuery) Parse(r *http.Request) (PaginatedUserQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return uq, err
+		}
+		uq.Limit = l
+	}
+
+	search := qs.Get("search")
+	if search != "" {
+		uq.Search = search
+	}
+
+	role := qs.Get("role")
+	if role != "" {
+		uq.Role = role
+	}
+
+	active := qs.Get("active")
+	if active != "" {
+		a, err := strconv.ParseBool(active)
+		if err != nil {
+			return uq, err
+		}
+		uq.Active = &a
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return uq, err
+		}
+		uq.Cursor = c
+	}
+
+	return uq, nil
+}
+
+// NextUserCursor returns the cursor for the page after users, or an empty string if users is the
+// last page.
+func NextUserCursor(users []User, limit int) (string, error) {
+	if len(users) == 0 || len(users) < limit {
+		return "", nil
+	}
+
+	last := users[len(users)-1]
+	createdAt, err := time.Parse(time.RFC3339, last.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: last.ID}.Encode(), nil
+}
+
+// PaginatedAuditQuery filters the audit trail, Since and Until are RFC3339 times.
+type PaginatedAuditQuery struct {
+	Limit      int        `json:"limit" validate:"gte=1,lte=100"`
+	ActorID    *int64     `json:"actor_id"`
+	Action     string     `json:"action" validate:"max=100"`
+	TargetType string     `json:"target_type" validate:"max=100"`
+	TargetID   *int64     `json:"target_id"`
+	Since      *time.Time `json:"since"`
+	Until      *time.Time `json:"until"`
+	Cursor     *Cursor    `json:"cursor"`
+}
+
+func (aq PaginatedAuditQuery) Parse(r *http.Request) (PaginatedAuditQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return aq, err
+		}
+		aq.Limit = l
+	}
+
+	actorID := qs.Get("actor_id")
+	if actorID != "" {
+		a, err := strconv.ParseInt(actorID, 10, 64)
+		if err != nil {
+			return aq, err
+		}
+		aq.ActorID = &a
+	}
+
+	action := qs.Get("action")
+	if action != "" {
+		aq.Action = action
+	}
+
+	targetType := qs.Get("target_type")
+	if targetType != "" {
+		aq.TargetType = targetType
+	}
+
+	targetID := qs.Get("target_id")
+	if targetID != "" {
+		t, err := strconv.ParseInt(targetID, 10, 64)
+		if err != nil {
+			return aq, err
+		}
+		aq.TargetID = &t
+	}
+
+	since := qs.Get("since")
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return aq, err
+		}
+		aq.Since = &t
+	}
+
+	until := qs.Get("until")
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return aq, err
+		}
+		aq.Until = &t
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return aq, err
+		}
+		aq.Cursor = c
+	}
+
+	return aq, nil
+}
+
+// NextAuditCursor returns the cursor for the page after events, or an empty string if events is
+// the last page.
+func NextAuditCursor(events []AuditEvent, limit int) (string, error) {
+	if len(events) == 0 || len(events) < limit {
+		return "", nil
+	}
+
+	last := events[len(events)-1]
+	createdAt, err := time.Parse(time.RFC3339, last.CreatedAt)
 	if err != nil {
-		return ""
+		return "", err
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: last.ID}.Encode(), nil
+}
+
+// PaginatedReportQuery filters the moderation queue, it lists the open reports by default.
+type PaginatedReportQuery struct {
+	Limit      int     `json:"limit" validate:"gte=1,lte=50"`
+	Status     string  `json:"status" validate:"oneof=open claimed resolved"`
+	TargetType string  `json:"target_type" validate:"omitempty,oneof=post comment"`
+	Reason     string  `json:"reason" validate:"max=50"`
+	ClaimedBy  *int64  `json:"claimed_by"`
+	Cursor     *Cursor `json:"cursor"`
+}
+
+func (rq PaginatedReportQuery) Parse(r *http.Request) (PaginatedReportQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return rq, err
+		}
+		rq.Limit = l
+	}
+
+	status := qs.Get("status")
+	if status != "" {
+		rq.Status = status
+	}
+
+	targetType := qs.Get("target_type")
+	if targetType != "" {
+		rq.TargetType = targetType
+	}
+
+	reason := qs.Get("reason")
+	if reason != "" {
+		rq.Reason = reason
+	}
+
+	claimedBy := qs.Get("claimed_by")
+	if claimedBy != "" {
+		c, err := strconv.ParseInt(claimedBy, 10, 64)
+		if err != nil {
+			return rq, err
+		}
+		rq.ClaimedBy = &c
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return rq, err
+		}
+		rq.Cursor = c
+	}
+
+	return rq, nil
+}
+
+// NextReportCursor returns the cursor for the page after reports, or an empty string if reports
+// is the last page.
+func NextReportCursor(reports []Report, limit int) (string, error) {
+	if len(reports) == 0 || len(reports) < limit {
+		return "", nil
+	}
+
+	last := reports[len(reports)-1]
+	createdAt, err := time.Parse(time.RFC3339, last.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: last.ID}.Encode(), nil
+}
+
+// PaginatedSearchQuery is a search across posts, comments and users. Query uses the web search
+// syntax: quoted phrases, or and - to exclude words.
+type PaginatedSearchQuery struct {
+	Query  string   `json:"q" validate:"required,max=100"`
+	Types  []string `json:"types" validate:"max=3,dive,oneof=post comment user"`
+	Limit  int      `json:"limit" validate:"gte=1,lte=50"`
+	Offset int      `json:"offset" validate:"gte=0,lte=1000"`
+}
+
+func (sq PaginatedSearchQuery) Parse(r *http.Request) (PaginatedSearchQuery, error) {
+	qs := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(qs.Get("q"))
+
+	types := qs.Get("types")
+	if types != "" {
+		sq.Types = strings.Split(types, ",")
+	}
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, err
+		}
+		sq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return sq, err
+		}
+		sq.Offset = o
+	}
+
+	return sq, nil
+}
+
+// PaginatedExploreQuery pages through the trending posts with the tag and search filters of the
+// feed. The ranking is precomputed so the filters are applied to it rather than in SQL.
+type PaginatedExploreQuery struct {
+	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
+	Offset int      `json:"offset" validate:"gte=0"`
+	Tags   []string `json:"tags" validate:"max=5"`
+	Search string   `json:"search" validate:"max=100"`
+}
+
+func (eq PaginatedExploreQuery) Parse(r *http.Request) (PaginatedExploreQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return eq, err
+		}
+		eq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return eq, err
+		}
+		eq.Offset = o
+	}
+
+	tags := qs.Get("tags")
+	if tags != "" {
+		eq.Tags = NormalizeTags(strings.Split(tags, ","))
+	}
+
+	search := qs.Get("search")
+	if search != "" {
+		eq.Search = search
+	}
+
+	return eq, nil
+}
+
+// Page returns the page of the posts having all the tags and matching the search, like the
+// filters of GetUserFeed.
+func (eq PaginatedExploreQuery) Page(posts []TrendingPost) []TrendingPost {
+	search := strings.ToLower(eq.Search)
+
+	page := []TrendingPost{}
+	skipped := 0
+	for _, post := range posts {
+		if len(page) == eq.Limit {
+			break
+		}
+
+		if !containsAll(post.Tags, eq.Tags) {
+			continue
+		}
+
+		if search != "" && !strings.Contains(strings.ToLower(post.Title), search) &&
+			!strings.Contains(strings.ToLower(post.Content), search) {
+			continue
+		}
+
+		if skipped < eq.Offset {
+			skipped++
+			continue
+		}
+
+		page = append(page, post)
+	}
+
+	return page
+}
+
+func containsAll(values, wanted []string) bool {
+	for _, w := range wanted {
+		if !slices.Contains(values, w) {
+			return false
+		}
 	}
-	return t.Format(time.DateTime)
+	return true
 }
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaginatedCommentQueryParse(t *testing.T) {
+	defaults := PaginatedCommentQuery{Limit: 20, Depth: 5}
+
+	t.Run("keeps the defaults", func(t *testing.T) {
+		cq, err := defaults.Parse(httptest.NewRequest("GET", "/v1/posts/1/comments", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cq.Limit != 20 || cq.Offset != 0 || cq.Depth != 5 || cq.ParentID != nil {
+			t.Errorf("expected the defaults but got %+v", cq)
+		}
+	})
+
+	t.Run("reads the query string", func(t *testing.T) {
+		cq, err := defaults.Parse(httptest.NewRequest(
+			"GET", "/v1/posts/1/comments?limit=2&offset=4&depth=3&parent_id=7", nil,
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cq.Limit != 2 || cq.Offset != 4 || cq.Depth != 3 || cq.ParentID == nil ||
+			*cq.ParentID != 7 {
+			t.Errorf("expected the query string values but got %+v", cq)
+		}
+	})
+
+	for _, query := range []string{"limit=a", "offset=a", "depth=a", "parent_id=a"} {
+		t.Run("rejects "+query, func(t *testing.T) {
+			if _, err := defaults.Parse(
+				httptest.NewRequest("GET", "/v1/posts/1/comments?"+query, nil),
+			); err == nil {
+				t.Errorf("expected an error for %s", query)
+			}
+		})
+	}
+}
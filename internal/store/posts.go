@@ -9,16 +9,18 @@ import (
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID        int64           `json:"id"`
+	Content   string          `json:"content"`
+	Title     string          `json:"title"`
+	UserID    int64           `json:"user_id"`
+	Tags      []string        `json:"tags"`
+	Entities  []Entity        `json:"entities"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+	Version   int             `json:"version"`
+	Comments  []Comment       `json:"comments"`
+	User      User            `json:"user"`
+	Reactions ReactionSummary `json:"reactions"`
 }
 
 // This will be used within the feed
@@ -27,34 +29,60 @@ type PostWithMetadata struct {
 	CommentCount int `json:"comments_count"`
 }
 
+// TrendingPost is a recent post ranked by its engagement on the explore feed
+type TrendingPost struct {
+	PostWithMetadata
+	Score float64 `json:"score"`
+}
+
 type PostStore struct {
 	db *pgxpool.Pool
 }
 
-func (s *PostStore) Create(ctx context.Context, post *Post) error {
+// Create inserts the post, links it to its tags, which are normalized, and records the users it
+// mentions, enqueuing the emails of mentionEmail for them.
+func (s *PostStore) Create(
+	ctx context.Context, post *Post, mentionEmail MentionEmailFunc,
+) error {
 	query := `
-		INSERT INTO posts (content, title, user_id, tags)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO posts (content, title, user_id, tags, entities)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	post.Tags = NormalizeTags(post.Tags)
+	if post.Entities == nil {
+		post.Entities = []Entity{}
+	}
 
 	var createdAt, updatedAt time.Time
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(
+			queryCtx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			post.Tags, // pgx supports slices for array types directly
+			post.Entities,
+		).Scan(
+			&post.ID,
+			&createdAt,
+			&updatedAt,
+		); err != nil {
+			return err
+		}
 
-	if err := s.db.QueryRow(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		post.Tags, // pgx supports slices for array types directly
-	).Scan(
-		&post.ID,
-		&createdAt,
-		&updatedAt,
-	); err != nil {
+		if err := setPostTags(ctx, tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+
+		return setMentions(ctx, tx, "post_id", post.ID, post.ID, post.Entities, mentionEmail)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -66,9 +94,9 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 
 func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 	query := `
-		SELECT title, content, user_id, tags, created_at, updated_at, version
+		SELECT title, content, user_id, tags, entities, created_at, updated_at, version
 		FROM posts
-		WHERE id=$1
+		WHERE id=$1 AND hidden_at IS NULL AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -82,6 +110,7 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		&post.Content,
 		&post.UserID,
 		&post.Tags,
+		&post.Entities,
 		&createdAt,
 		&updatedAt,
 		&post.Version,
@@ -100,8 +129,34 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 	return post, nil
 }
 
-func (s *PostStore) Delete(ctx context.Context, postID int64) error {
-	query := `DELETE FROM posts WHERE id=$1`
+// Delete marks the post as deleted by the user, it can be restored until it is purged.
+func (s *PostStore) Delete(ctx context.Context, postID, deletedBy int64) error {
+	query := `
+		UPDATE posts SET deleted_at = now(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, query, postID, deletedBy)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Restore brings back a deleted post with its comments
+func (s *PostStore) Restore(ctx context.Context, postID int64) error {
+	query := `
+		UPDATE posts SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -118,59 +173,153 @@ func (s *PostStore) Delete(ctx context.Context, postID int64) error {
 	return nil
 }
 
-func (s *PostStore) Update(ctx context.Context, post *Post) error {
+// Purge removes for good the posts deleted before the given time and their comments. It returns
+// the number of posts removed.
+func (s *PostStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		query := /* sql */ `
+			DELETE FROM comments WHERE post_id IN (
+				SELECT id FROM posts WHERE deleted_at < $1
+			)
+		`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(queryCtx, query, deletedBefore); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(queryCtx, `DELETE FROM posts WHERE deleted_at < $1`, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		purged = res.RowsAffected()
+		return nil
+	})
+
+	return purged, err
+}
+
+func (s *PostStore) Update(
+	ctx context.Context, post *Post, mentionEmail MentionEmailFunc,
+) error {
 	// Optimistic locking: only update if the version matches
 	// This prevents lost updates in concurrent scenarios
 	// The version is incremented on each successful update
 	query := `
 		UPDATE posts
-		SET title=$1, content=$2, updated_at=$3, version=version + 1
-		WHERE id=$4 AND version=$5
+		SET title=$1, content=$2, tags=$3, entities=$4, updated_at=$5, version=version + 1
+		WHERE id=$6 AND version=$7
 		RETURNING updated_at, version
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	post.Tags = NormalizeTags(post.Tags)
+	if post.Entities == nil {
+		post.Entities = []Entity{}
+	}
 
 	var updatedAt time.Time
-	if err := s.db.QueryRow(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		time.Now(),
-		post.ID,
-		post.Version,
-	).Scan(&updatedAt, &post.Version); err != nil {
-		switch err {
-		case pgx.ErrNoRows:
-			return ErrNotFound
-		default:
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := tx.QueryRow(
+			queryCtx,
+			query,
+			post.Title,
+			post.Content,
+			post.Tags,
+			post.Entities,
+			time.Now(),
+			post.ID,
+			post.Version,
+		).Scan(&updatedAt, &post.Version); err != nil {
+			switch err {
+			case pgx.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := setPostTags(ctx, tx, post.ID, post.Tags); err != nil {
 			return err
 		}
+
+		return setMentions(ctx, tx, "post_id", post.ID, post.ID, post.Entities, mentionEmail)
+	})
+	if err != nil {
+		return err
 	}
 
 	post.UpdatedAt = updatedAt.Format(time.RFC3339)
 	return nil
 }
 
+// GetUserFeed returns the posts of the user, of the users it follows and with the tags it follows.
 func (s *PostStore) GetUserFeed(
 	ctx context.Context, userID int64, pq PaginatedFeedQuery,
 ) ([]PostWithMetadata, error) {
 
+	// Keyset pagination: continue after the (created_at, id) of the last post of the previous
+	// page rather than skipping rows with OFFSET, so new posts do not shift the pages.
+	keysetOp := "<"
+	if pq.Sort == "asc" {
+		keysetOp = ">"
+	}
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if pq.Cursor != nil {
+		cursorCreatedAt = &pq.Cursor.CreatedAt
+		cursorID = pq.Cursor.ID
+	}
+
+	since, until, err := pq.TimeRange()
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, u.username,
-			COUNT(c.id) AS comments_count
+		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities,
+			u.username, COUNT(c.id) AS comments_count,
+			(
+				SELECT COALESCE(jsonb_object_agg(rc.kind, rc.count), '{}'::jsonb)
+				FROM (
+					SELECT pr.kind, COUNT(*) AS count FROM post_reactions pr
+					WHERE pr.post_id = p.id GROUP BY pr.kind
+				) rc
+			) AS reaction_counts,
+			(
+				SELECT pr.kind FROM post_reactions pr WHERE pr.post_id = p.id AND pr.user_id = $1
+			) AS viewer_reaction
 		FROM posts p
-			LEFT JOIN comments c ON c.post_id = p.id
+			LEFT JOIN comments c
+				ON c.post_id = p.id AND c.hidden_at IS NULL AND c.deleted_at IS NULL
 			LEFT JOIN users u ON p.user_id = u.id
-			LEFT JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
 		WHERE
-			(f.user_id = $1 OR p.user_id = $1)
+			(
+				p.user_id = $1
+				OR EXISTS (
+					SELECT 1 FROM followers f WHERE f.user_id = $1 AND f.follower_id = p.user_id
+				)
+				OR EXISTS (
+					SELECT 1 FROM post_tags pt
+					JOIN tag_followers tf ON tf.tag_id = pt.tag_id
+					WHERE pt.post_id = p.id AND tf.user_id = $1
+				)
+			)
+			AND p.hidden_at IS NULL AND p.deleted_at IS NULL
 			AND (p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%')
 			AND (p.tags @> $5 OR $5 = '{}')
+			AND ($6::timestamptz IS NULL OR (p.created_at, p.id) ` + keysetOp + ` ($6, $7::bigint))
+			AND ($8::timestamptz IS NULL OR p.created_at >= $8)
+			AND ($9::timestamptz IS NULL OR p.created_at <= $9)
 		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + pq.Sort + `
+		ORDER BY p.created_at ` + pq.Sort + `, p.id ` + pq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 
@@ -185,6 +334,10 @@ func (s *PostStore) GetUserFeed(
 		pq.Offset,
 		pq.Search,
 		pq.Tags,
+		cursorCreatedAt,
+		cursorID,
+		since,
+		until,
 	)
 	if err != nil {
 		return nil, err
@@ -204,13 +357,20 @@ func (s *PostStore) GetUserFeed(
 			&createdAt,
 			&p.Version,
 			&p.Tags,
+			&p.Entities,
 			&p.User.Username,
 			&p.CommentCount,
+			&p.Reactions.Counts,
+			&p.Reactions.ViewerReaction,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		for _, count := range p.Reactions.Counts {
+			p.Reactions.Total += count
+		}
+
 		p.CreatedAt = createdAt.Format(time.RFC3339)
 
 		feed = append(feed, p)
@@ -218,3 +378,80 @@ func (s *PostStore) GetUserFeed(
 
 	return feed, nil
 }
+
+// GetTrending ranks the visible posts created in the window by engagement. Comments weigh
+// twice as much as reactions and the score decays with the age of the post, so a new post with
+// some activity ranks above an older one with a bit more.
+func (s *PostStore) GetTrending(
+	ctx context.Context, window time.Duration, limit int,
+) ([]TrendingPost, error) {
+	query := /* sql */ `
+		SELECT id, user_id, title, content, created_at, version, tags, entities, username,
+			comments_count, reaction_counts, reactions_total,
+			((2 * comments_count + reactions_total + 1) /
+				power(extract(epoch FROM now() - created_at) / 3600 + 2, 1.5))::float8 AS score
+		FROM (
+			SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
+				p.entities, u.username,
+				(
+					SELECT COUNT(*) FROM comments c
+					WHERE c.post_id = p.id AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+				) AS comments_count,
+				(
+					SELECT COALESCE(jsonb_object_agg(rc.kind, rc.count), '{}'::jsonb)
+					FROM (
+						SELECT pr.kind, COUNT(*) AS count FROM post_reactions pr
+						WHERE pr.post_id = p.id GROUP BY pr.kind
+					) rc
+				) AS reaction_counts,
+				(SELECT COUNT(*) FROM post_reactions pr WHERE pr.post_id = p.id) AS reactions_total
+			FROM posts p
+			JOIN users u ON u.id = p.user_id
+			WHERE p.created_at > now() - make_interval(secs => $1)
+				AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+		) engagement
+		ORDER BY score DESC, created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, window.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []TrendingPost{}
+	for rows.Next() {
+		var p TrendingPost
+		var createdAt time.Time
+		if err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Title,
+			&p.Content,
+			&createdAt,
+			&p.Version,
+			&p.Tags,
+			&p.Entities,
+			&p.User.Username,
+			&p.CommentCount,
+			&p.Reactions.Counts,
+			&p.Reactions.Total,
+			&p.Score,
+		); err != nil {
+			return nil, err
+		}
+		p.User.ID = p.UserID
+		p.CreatedAt = createdAt.Format(time.RFC3339)
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
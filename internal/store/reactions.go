@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReactionKind string
+
+// NOTE: Keep this list in sync with the CHECK constraint on post_reactions.kind
+const (
+	ReactionLike  ReactionKind = "like"
+	ReactionLove  ReactionKind = "love"
+	ReactionHaha  ReactionKind = "haha"
+	ReactionWow   ReactionKind = "wow"
+	ReactionSad   ReactionKind = "sad"
+	ReactionAngry ReactionKind = "angry"
+)
+
+var ReactionKinds = []ReactionKind{
+	ReactionLike, ReactionLove, ReactionHaha, ReactionWow, ReactionSad, ReactionAngry,
+}
+
+func (k ReactionKind) Valid() bool {
+	for _, kind := range ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type Reaction struct {
+	PostID    int64        `json:"post_id"`
+	UserID    int64        `json:"user_id"`
+	Kind      ReactionKind `json:"kind"`
+	CreatedAt string       `json:"created_at"`
+}
+
+// ReactionSummary is the engagement on a post as seen by the requesting user
+type ReactionSummary struct {
+	Counts         map[ReactionKind]int `json:"counts"`
+	Total          int                  `json:"total"`
+	ViewerReaction *ReactionKind        `json:"viewer_reaction"`
+}
+
+type ReactionStore struct {
+	db *pgxpool.Pool
+}
+
+// Set adds the reaction of a user to a post, replacing any previous reaction of that user.
+func (s *ReactionStore) Set(ctx context.Context, reaction *Reaction) error {
+	query := /* sql */ `
+		INSERT INTO post_reactions (post_id, user_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET kind = EXCLUDED.kind, created_at = now()
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var createdAt time.Time
+	if err := s.db.QueryRow(
+		ctx, query, reaction.PostID, reaction.UserID, reaction.Kind,
+	).Scan(&createdAt); err != nil {
+		return err
+	}
+
+	reaction.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+func (s *ReactionStore) Delete(ctx context.Context, reaction *Reaction) error {
+	query := /* sql */ `
+		DELETE FROM post_reactions
+		WHERE post_id = $1 AND user_id = $2 AND kind = $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.Exec(ctx, query, reaction.PostID, reaction.UserID, reaction.Kind)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ReactionStore) GetSummary(
+	ctx context.Context, postID, viewerID int64,
+) (*ReactionSummary, error) {
+	query := /* sql */ `
+		SELECT kind, COUNT(*), BOOL_OR(user_id = $2)
+		FROM post_reactions
+		WHERE post_id = $1
+		GROUP BY kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &ReactionSummary{Counts: map[ReactionKind]int{}}
+	for rows.Next() {
+		var kind ReactionKind
+		var count int
+		var isViewer bool
+		if err := rows.Scan(&kind, &count, &isViewer); err != nil {
+			return nil, err
+		}
+
+		summary.Counts[kind] = count
+		summary.Total += count
+		if isViewer {
+			summary.ViewerReaction = &kind
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,369 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Reported content
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+)
+
+// A report is open until a moderator claims it, only that moderator can resolve it.
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusClaimed  = "claimed"
+	ReportStatusResolved = "resolved"
+)
+
+// What resolving a report does to the reported content
+const (
+	ResolutionDismiss = "dismiss"
+	ResolutionHide    = "hide"
+	ResolutionDelete  = "delete"
+)
+
+// reportTargetTables maps the reported content to its table
+var reportTargetTables = map[string]string{
+	ReportTargetPost:    "posts",
+	ReportTargetComment: "comments",
+}
+
+type Report struct {
+	ID             int64  `json:"id"`
+	TargetType     string `json:"target_type"`
+	TargetID       int64  `json:"target_id"`
+	ReporterID     int64  `json:"reporter_id"`
+	Reason         string `json:"reason"`
+	Details        string `json:"details"`
+	Status         string `json:"status"`
+	ClaimedBy      *int64 `json:"claimed_by"`
+	ClaimedAt      string `json:"claimed_at,omitempty"`
+	Resolution     string `json:"resolution,omitempty"`
+	ResolutionNote string `json:"resolution_note,omitempty"`
+	ResolvedBy     *int64 `json:"resolved_by"`
+	ResolvedAt     string `json:"resolved_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type ReportStore struct {
+	db *pgxpool.Pool
+}
+
+const reportColumns = /* sql */ `
+	id, target_type, target_id, reporter_id, reason, details, status, claimed_by, claimed_at,
+	coalesce(resolution, ''), resolution_note, resolved_by, resolved_at, created_at
+`
+
+// Create files a report, a user reporting the same content twice is a conflict.
+func (s *ReportStore) Create(ctx context.Context, report *Report) error {
+	query := /* sql */ `
+		INSERT INTO reports (target_type, target_id, reporter_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var createdAt time.Time
+	if err := s.db.QueryRow(
+		ctx,
+		query,
+		report.TargetType,
+		report.TargetID,
+		report.ReporterID,
+		report.Reason,
+		report.Details,
+	).Scan(&report.ID, &report.Status, &createdAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+
+	report.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+func (s *ReportStore) GetByID(ctx context.Context, reportID int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	report, err := scanReport(s.db.QueryRow(ctx, query, reportID))
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// List returns the moderation queue, oldest reports first
+func (s *ReportStore) List(ctx context.Context, rq PaginatedReportQuery) ([]Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports
+		WHERE status = $2
+			AND ($3 = '' OR target_type = $3)
+			AND ($4 = '' OR reason = $4)
+			AND ($5::bigint IS NULL OR claimed_by = $5)
+			AND ($6::timestamptz IS NULL OR (created_at, id) > ($6, $7::bigint))
+		ORDER BY created_at ASC, id ASC
+		LIMIT $1
+	`
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if rq.Cursor != nil {
+		cursorCreatedAt = &rq.Cursor.CreatedAt
+		cursorID = rq.Cursor.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(
+		ctx,
+		query,
+		rq.Limit,
+		rq.Status,
+		rq.TargetType,
+		rq.Reason,
+		rq.ClaimedBy,
+		cursorCreatedAt,
+		cursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// Claim assigns an open report to a moderator. Claiming a report again is a no-op for the
+// moderator who holds it and a conflict for the others.
+func (s *ReportStore) Claim(ctx context.Context, reportID, moderatorID int64) (*Report, error) {
+	var report *Report
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var err error
+		report, err = lockReport(ctx, tx, reportID)
+		if err != nil {
+			return err
+		}
+
+		if report.isClaimedBy(moderatorID) {
+			return nil
+		}
+
+		// The claim of a moderator who was deleted is released
+		if report.Status != ReportStatusOpen && !report.isClaimedBy(0) {
+			return ErrConflict
+		}
+
+		query := /* sql */ `
+			UPDATE reports SET status = $1, claimed_by = $2, claimed_at = now()
+			WHERE id = $3
+			RETURNING ` + reportColumns
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		report, err = scanReport(
+			tx.QueryRow(queryCtx, query, ReportStatusClaimed, moderatorID, reportID),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Resolve applies the resolution to the reported content and resolves the report along with the
+// other pending reports of the same content. Only the moderator who claimed the report can
+// resolve it.
+func (s *ReportStore) Resolve(
+	ctx context.Context, reportID, moderatorID int64, resolution, note string,
+) (*Report, error) {
+	var report *Report
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var err error
+		report, err = lockReport(ctx, tx, reportID)
+		if err != nil {
+			return err
+		}
+
+		if !report.isClaimedBy(moderatorID) {
+			return ErrConflict
+		}
+
+		err = applyResolution(
+			ctx, tx, report.TargetType, report.TargetID, resolution, moderatorID,
+		)
+		if err != nil {
+			return err
+		}
+
+		query := /* sql */ `
+			UPDATE reports
+			SET status = $1, resolution = $2, resolution_note = $3, resolved_by = $4,
+				resolved_at = now()
+			WHERE target_type = $5 AND target_id = $6 AND status <> $1
+		`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(
+			queryCtx,
+			query,
+			ReportStatusResolved,
+			resolution,
+			note,
+			moderatorID,
+			report.TargetType,
+			report.TargetID,
+		); err != nil {
+			return err
+		}
+
+		report, err = lockReport(ctx, tx, reportID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// isClaimedBy tells if the moderator holds the report, 0 matches a released claim
+func (r *Report) isClaimedBy(moderatorID int64) bool {
+	if r.Status != ReportStatusClaimed {
+		return false
+	}
+
+	if r.ClaimedBy == nil {
+		return moderatorID == 0
+	}
+
+	return *r.ClaimedBy == moderatorID
+}
+
+func lockReport(ctx context.Context, tx pgx.Tx, reportID int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1 FOR UPDATE`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	report, err := scanReport(tx.QueryRow(ctx, query, reportID))
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// applyResolution hides or deletes the reported content on behalf of the moderator. Deleted
+// content can be restored by the admins until it is purged.
+func applyResolution(
+	ctx context.Context,
+	tx pgx.Tx,
+	targetType string,
+	targetID int64,
+	resolution string,
+	moderatorID int64,
+) error {
+	table, ok := reportTargetTables[targetType]
+	if !ok {
+		return errors.New("unknown report target " + targetType)
+	}
+
+	var query string
+	args := []any{targetID}
+	switch resolution {
+	case ResolutionDismiss:
+		return nil
+	case ResolutionHide:
+		query = `UPDATE ` + table + ` SET hidden_at = now() WHERE id = $1 AND hidden_at IS NULL`
+	case ResolutionDelete:
+		query = `UPDATE ` + table + ` SET deleted_at = now(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL`
+		args = append(args, moderatorID)
+	default:
+		return errors.New("unknown resolution " + resolution)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, args...)
+	return err
+}
+
+func scanReport(row pgx.Row) (*Report, error) {
+	report := &Report{}
+	var claimedAt, resolvedAt *time.Time
+	var createdAt time.Time
+	if err := row.Scan(
+		&report.ID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.ReporterID,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.ClaimedBy,
+		&claimedAt,
+		&report.Resolution,
+		&report.ResolutionNote,
+		&report.ResolvedBy,
+		&resolvedAt,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if claimedAt != nil {
+		report.ClaimedAt = claimedAt.Format(time.RFC3339)
+	}
+	if resolvedAt != nil {
+		report.ResolvedAt = resolvedAt.Format(time.RFC3339)
+	}
+	report.CreatedAt = createdAt.Format(time.RFC3339)
+
+	return report, nil
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Searched content
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+	SearchTypeUser    = "user"
+)
+
+// SearchResult is a post, comment or user matching a search. Title and Snippet are HTML escaped
+// with the matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Type      string  `json:"type"`
+	ID        int64   `json:"id"`
+	PostID    *int64  `json:"post_id,omitempty"`
+	UserID    int64   `json:"user_id"`
+	Username  string  `json:"username"`
+	Title     string  `json:"title,omitempty"`
+	Snippet   string  `json:"snippet"`
+	Rank      float32 `json:"rank"`
+	CreatedAt string  `json:"created_at"`
+}
+
+type SearchStore struct {
+	db *pgxpool.Pool
+}
+
+// The matches are delimited with control characters so that the content can be escaped before
+// they are turned into <mark> tags.
+const (
+	matchStart = "\x02"
+	matchStop  = "\x03"
+)
+
+var highlighter = strings.NewReplacer(matchStart, "<mark>", matchStop, "</mark>")
+
+// The wildcards of ILIKE are matched literally in the searched usernames
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search ranks the visible posts and comments by full text relevance and the active users by
+// the similarity of their username. Both ranks go from 0 to 1, ts_rank normalizes its rank with
+// rank / (rank + 1), so the results are merged into a single list. The snippets are only built for
+// the returned page.
+func (s *SearchStore) Search(ctx context.Context, sq PaginatedSearchQuery) ([]SearchResult, error) {
+	query := /* sql */ `
+		WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) AS query
+		), results AS (
+			SELECT 'post' AS type, p.id, p.id AS post_id, p.user_id, u.username, p.title,
+				p.content, ts_rank(p.search_vector, q.query, 32) AS rank, p.created_at
+			FROM posts p
+			CROSS JOIN q
+			JOIN users u ON u.id = p.user_id
+			WHERE $2::boolean AND p.search_vector @@ q.query
+				AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT 'comment', c.id, c.post_id, c.user_id, u.username, '', c.content,
+				ts_rank(c.search_vector, q.query, 32), c.created_at
+			FROM comments c
+			CROSS JOIN q
+			JOIN users u ON u.id = c.user_id
+			JOIN posts p ON p.id = c.post_id
+			WHERE $3::boolean AND c.search_vector @@ q.query
+				AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+				AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT 'user', u.id, NULL, u.id, u.username, '', '', similarity(u.username, $1),
+				u.created_at
+			FROM users u
+			WHERE $4::boolean AND u.is_active = true AND u.deactivated_at IS NULL
+				AND (u.username % $1 OR u.username ILIKE '%' || $8 || '%')
+			ORDER BY rank DESC, created_at DESC, type, id
+			LIMIT $5 OFFSET $6
+		)
+		SELECT r.type, r.id, r.post_id, r.user_id, r.username,
+			ts_headline(
+				'english', translate(r.title, E'\x02\x03', ''), q.query,
+				$7 || ', HighlightAll=true'
+			),
+			CASE WHEN r.type = 'user' THEN r.username
+				ELSE ts_headline(
+					'english', translate(r.content, E'\x02\x03', ''), q.query,
+					$7 || ', MaxFragments=2'
+				)
+			END,
+			r.rank, r.created_at
+		FROM results r
+		CROSS JOIN q
+		ORDER BY r.rank DESC, r.created_at DESC, r.type, r.id
+	`
+
+	// The delimiters are stripped from the content beforehand so that it cannot inject marks
+	headlineOptions := "StartSel=" + matchStart + ", StopSel=" + matchStop +
+		", MinWords=10, MaxWords=30"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(
+		ctx,
+		query,
+		sq.Query,
+		sq.includes(SearchTypePost),
+		sq.includes(SearchTypeComment),
+		sq.includes(SearchTypeUser),
+		sq.Limit,
+		sq.Offset,
+		headlineOptions,
+		likeEscaper.Replace(sq.Query),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		var createdAt time.Time
+		if err := rows.Scan(
+			&r.Type,
+			&r.ID,
+			&r.PostID,
+			&r.UserID,
+			&r.Username,
+			&r.Title,
+			&r.Snippet,
+			&r.Rank,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		r.Title = highlight(r.Title)
+		r.Snippet = highlight(r.Snippet)
+		r.CreatedAt = createdAt.Format(time.RFC3339)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// includes tells if the results of the given type were asked for, all types are by default
+func (sq PaginatedSearchQuery) includes(searchType string) bool {
+	return len(sq.Types) == 0 || slices.Contains(sq.Types, searchType)
+}
+
+func highlight(text string) string {
+	return highlighter.Replace(html.EscapeString(text))
+}
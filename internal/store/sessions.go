@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Session struct {
+	ID        string `json:"id"`
+	UserID    int64  `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+	Expiry    string `json:"expiry"`
+}
+
+type SessionStore struct {
+	db *pgxpool.Pool
+}
+
+// Create starts a session for the user along with its first refresh token. The token must
+// already be hashed.
+func (s *SessionStore) Create(
+	ctx context.Context, session *Session, refreshToken string, expiry time.Duration,
+) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		query := /* sql */ `
+			INSERT INTO sessions (id, user_id, expiry)
+			VALUES ($1, $2, $3)
+			RETURNING created_at, expiry
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var createdAt, sessionExpiry time.Time
+		if err := tx.QueryRow(
+			ctx, query, session.ID, session.UserID, time.Now().Add(expiry),
+		).Scan(&createdAt, &sessionExpiry); err != nil {
+			return err
+		}
+
+		session.CreatedAt = createdAt.Format(time.RFC3339)
+		session.Expiry = sessionExpiry.Format(time.RFC3339)
+
+		return s.createRefreshToken(ctx, tx, refreshToken, session.ID, sessionExpiry)
+	})
+}
+
+// Rotate exchanges a refresh token for a new one and returns the session it belongs to. Both
+// tokens must already be hashed. Presenting a token that was already rotated revokes the session
+// and returns ErrTokenReused.
+func (s *SessionStore) Rotate(ctx context.Context, refreshToken, newToken string) (*Session, error) {
+	session := &Session{}
+	reused := false
+
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		query := /* sql */ `
+			SELECT s.id::text, s.user_id, s.created_at, s.expiry, rt.used_at IS NOT NULL
+			FROM refresh_tokens rt
+			JOIN sessions s ON s.id = rt.session_id
+			WHERE rt.token = $1 AND rt.expiry > $2 AND s.revoked_at IS NULL
+			FOR UPDATE OF rt
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var createdAt, expiry time.Time
+		var used bool
+		if err := tx.QueryRow(ctx, query, refreshToken, time.Now()).Scan(
+			&session.ID,
+			&session.UserID,
+			&createdAt,
+			&expiry,
+			&used,
+		); err != nil {
+			switch err {
+			case pgx.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		session.CreatedAt = createdAt.Format(time.RFC3339)
+		session.Expiry = expiry.Format(time.RFC3339)
+
+		if used {
+			// Commit the revocation, the error is returned once the transaction is done
+			reused = true
+			return s.revoke(ctx, tx, session.ID)
+		}
+
+		query = /* sql */ `UPDATE refresh_tokens SET used_at = now() WHERE token = $1`
+		if _, err := tx.Exec(ctx, query, refreshToken); err != nil {
+			return err
+		}
+
+		return s.createRefreshToken(ctx, tx, newToken, session.ID, expiry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, ErrTokenReused
+	}
+
+	return session, nil
+}
+
+// Revoke ends a session, the access and refresh tokens issued for it are no longer valid.
+func (s *SessionStore) Revoke(ctx context.Context, sessionID string) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		return s.revoke(ctx, tx, sessionID)
+	})
+}
+
+// RevokeAllForUser ends every session of a user, e.g. when their password changes.
+func (s *SessionStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		return revokeUserSessions(ctx, tx, userID)
+	})
+}
+
+// RevokeToken revokes a single access token by its jti until it expires.
+func (s *SessionStore) RevokeToken(ctx context.Context, jti string, expiry time.Time) error {
+	query := /* sql */ `
+		INSERT INTO revoked_tokens (jti, expiry)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, jti, expiry)
+	return err
+}
+
+// IsRevoked tells whether an access token was revoked, either on its own or with its session.
+func (s *SessionStore) IsRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	query := /* sql */ `
+		SELECT
+			EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR NOT EXISTS (
+				SELECT 1 FROM sessions WHERE id = $2 AND revoked_at IS NULL AND expiry > $3
+			)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var revoked bool
+	if err := s.db.QueryRow(ctx, query, jti, sessionID, time.Now()).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func (s *SessionStore) revoke(ctx context.Context, tx pgx.Tx, sessionID string) error {
+	query := /* sql */ `
+		UPDATE sessions SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, sessionID)
+	return err
+}
+
+func (s *SessionStore) createRefreshToken(
+	ctx context.Context, tx pgx.Tx, token, sessionID string, expiry time.Time,
+) error {
+	query := /* sql */ `
+		INSERT INTO refresh_tokens (token, session_id, expiry)
+		VALUES ($1, $2, $3)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, token, sessionID, expiry)
+	return err
+}
+
+// revokeUserSessions is shared with the user store so that a password reset and the revocation of
+// the sessions happen in the same transaction.
+func revokeUserSessions(ctx context.Context, tx pgx.Tx, userID int64) error {
+	query := /* sql */ `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, userID)
+	return err
+}
@@ -14,36 +14,105 @@ var (
 	ErrConflict          = errors.New("record conflict")
 	ErrDuplicateEmail    = errors.New("duplicate email")
 	ErrDuplicateUsername = errors.New("duplicate username")
+	ErrInvalidReaction   = errors.New("invalid reaction kind")
+	ErrTokenReused       = errors.New("token already used")
 	QueryTimeoutDuration = time.Second * 5
 )
 
+// DefaultLocale of the users that did not choose one
+const DefaultLocale = "en"
+
 type Storage struct {
 	Posts interface {
-		Create(context.Context, *Post) error
+		Create(context.Context, *Post, MentionEmailFunc) error
 		GetByID(context.Context, int64) (*Post, error)
-		Delete(context.Context, int64) error
-		Update(context.Context, *Post) error
+		Delete(context.Context, int64, int64) error
+		Restore(context.Context, int64) error
+		Purge(context.Context, time.Time) (int64, error)
+		Update(context.Context, *Post, MentionEmailFunc) error
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetTrending(context.Context, time.Duration, int) ([]TrendingPost, error)
 	}
 	Users interface {
 		GetByEmail(context.Context, string) (*User, error)
 		GetByID(context.Context, int64) (*User, error)
+		GetByUsernames(context.Context, []string) ([]User, error)
 		Create(context.Context, pgx.Tx, *User) error
-		CreateAndInvite(context.Context, *User, string, time.Duration) error
+		CreateAndInvite(context.Context, *User, string, time.Duration, *OutboxEmail) error
+		RotateInvitation(
+			context.Context, string, string, time.Duration, func(*User) (*OutboxEmail, error),
+		) error
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
+		CreatePasswordReset(context.Context, int64, string, time.Duration, *OutboxEmail) error
+		ResetPassword(context.Context, string, string) error
+		UpdateLocale(context.Context, int64, string) error
+		GetAccount(context.Context, int64) (*User, error)
+		Search(context.Context, PaginatedUserQuery) ([]User, error)
+		SetRole(context.Context, int64, int64) error
+		Deactivate(context.Context, int64, int64) error
+		Reactivate(context.Context, int64) error
 	}
 	Comments interface {
-		Create(context.Context, *Comment) error
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		Create(context.Context, *Comment, MentionEmailFunc) error
+		GetByID(context.Context, int64) (*Comment, error)
+		GetByPostID(context.Context, int64, PaginatedCommentQuery) ([]Comment, error)
+		Update(context.Context, *Comment, MentionEmailFunc) error
+		Delete(context.Context, int64, int64) error
+		Restore(context.Context, int64) error
+		Purge(context.Context, time.Time) (int64, error)
 	}
 	Followers interface {
 		Follow(context.Context, int64, int64) error
 		Unfollow(context.Context, int64, int64) error
+		GetFollowers(context.Context, int64, PaginatedFollowQuery) ([]FollowEntry, error)
+		GetFollowing(context.Context, int64, PaginatedFollowQuery) ([]FollowEntry, error)
+		GetCounts(context.Context, int64) (int, int, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+	Reactions interface {
+		Set(context.Context, *Reaction) error
+		Delete(context.Context, *Reaction) error
+		GetSummary(context.Context, int64, int64) (*ReactionSummary, error)
+	}
+	Sessions interface {
+		Create(context.Context, *Session, string, time.Duration) error
+		Rotate(context.Context, string, string) (*Session, error)
+		Revoke(context.Context, string) error
+		RevokeAllForUser(context.Context, int64) error
+		RevokeToken(context.Context, string, time.Time) error
+		IsRevoked(context.Context, string, string) (bool, error)
+	}
+	Outbox interface {
+		Enqueue(context.Context, *OutboxEmail) error
+		Claim(context.Context, int, time.Duration) ([]OutboxEmail, error)
+		MarkSent(context.Context, int64) error
+		MarkFailed(context.Context, int64, time.Time, string) error
+		MarkDead(context.Context, int64, string) error
+	}
+	Reports interface {
+		Create(context.Context, *Report) error
+		GetByID(context.Context, int64) (*Report, error)
+		List(context.Context, PaginatedReportQuery) ([]Report, error)
+		Claim(context.Context, int64, int64) (*Report, error)
+		Resolve(context.Context, int64, int64, string, string) (*Report, error)
+	}
+	Search interface {
+		Search(context.Context, PaginatedSearchQuery) ([]SearchResult, error)
+	}
+	Tags interface {
+		GetByName(context.Context, string) (*Tag, error)
+		GetPosts(context.Context, int64, int64, PaginatedTagPostsQuery) ([]PostWithMetadata, error)
+		GetTrending(context.Context, time.Duration, int) ([]Tag, error)
+		Follow(context.Context, int64, int64) error
+		Unfollow(context.Context, int64, int64) error
+	}
+	Audit interface {
+		Record(context.Context, *AuditEvent) error
+		List(context.Context, PaginatedAuditQuery) ([]AuditEvent, error)
+	}
 }
 
 func NewPostgresStorage(db *pgxpool.Pool) *Storage {
@@ -53,6 +122,13 @@ func NewPostgresStorage(db *pgxpool.Pool) *Storage {
 		Comments:  &CommentStore{db},
 		Followers: &FollowerStore{db},
 		Roles:     &RoleStore{db},
+		Reactions: &ReactionStore{db},
+		Sessions:  &SessionStore{db},
+		Outbox:    &OutboxStore{db},
+		Reports:   &ReportStore{db},
+		Search:    &SearchStore{db},
+		Tags:      &TagStore{db},
+		Audit:     &AuditStore{db},
 	}
 }
 
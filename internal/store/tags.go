@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// A tag is made of letters, digits, underscores and hyphens, optionally prefixed with a #
+var tagPattern = regexp.MustCompile(`^#?[\p{L}\p{N}_-]{1,30}$`)
+
+type Tag struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	PostsCount int    `json:"posts_count"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type TagStore struct {
+	db *pgxpool.Pool
+}
+
+// IsValidTag tells if the tag can be given to a post, before it is normalized
+func IsValidTag(tag string) bool {
+	return tagPattern.MatchString(strings.TrimSpace(tag))
+}
+
+// NormalizeTag case folds the tag and strips its # prefix
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// NormalizeTags normalizes the tags and drops the empty and duplicate ones, keeping their order.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// GetByName returns the tag with the number of visible posts tagged with it
+func (s *TagStore) GetByName(ctx context.Context, name string) (*Tag, error) {
+	query := /* sql */ `
+		SELECT t.id, t.name, t.created_at, (
+			SELECT COUNT(*) FROM post_tags pt
+			JOIN posts p ON p.id = pt.post_id
+			WHERE pt.tag_id = t.id AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+		)
+		FROM tags t
+		WHERE t.name = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tag := &Tag{}
+	var createdAt time.Time
+	if err := s.db.QueryRow(ctx, query, NormalizeTag(name)).Scan(
+		&tag.ID,
+		&tag.Name,
+		&createdAt,
+		&tag.PostsCount,
+	); err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	tag.CreatedAt = createdAt.Format(time.RFC3339)
+	return tag, nil
+}
+
+// GetPosts returns the visible posts with the tag, newest first
+func (s *TagStore) GetPosts(
+	ctx context.Context, tagID, viewerID int64, tq PaginatedTagPostsQuery,
+) ([]PostWithMetadata, error) {
+	query := /* sql */ `
+		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities,
+			u.username,
+			(
+				SELECT COUNT(*) FROM comments c
+				WHERE c.post_id = p.id AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+			) AS comments_count,
+			(
+				SELECT COALESCE(jsonb_object_agg(rc.kind, rc.count), '{}'::jsonb)
+				FROM (
+					SELECT pr.kind, COUNT(*) AS count FROM post_reactions pr
+					WHERE pr.post_id = p.id GROUP BY pr.kind
+				) rc
+			) AS reaction_counts,
+			(
+				SELECT pr.kind FROM post_reactions pr WHERE pr.post_id = p.id AND pr.user_id = $2
+			) AS viewer_reaction
+		FROM post_tags pt
+		JOIN posts p ON p.id = pt.post_id
+		JOIN users u ON u.id = p.user_id
+		WHERE pt.tag_id = $1
+			AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+			AND ($4::timestamptz IS NULL OR (p.created_at, p.id) < ($4, $5::bigint))
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $3
+	`
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if tq.Cursor != nil {
+		cursorCreatedAt = &tq.Cursor.CreatedAt
+		cursorID = tq.Cursor.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, tagID, viewerID, tq.Limit, cursorCreatedAt, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		var createdAt time.Time
+		if err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Title,
+			&p.Content,
+			&createdAt,
+			&p.Version,
+			&p.Tags,
+			&p.Entities,
+			&p.User.Username,
+			&p.CommentCount,
+			&p.Reactions.Counts,
+			&p.Reactions.ViewerReaction,
+		); err != nil {
+			return nil, err
+		}
+
+		for _, count := range p.Reactions.Counts {
+			p.Reactions.Total += count
+		}
+
+		p.User.ID = p.UserID
+		p.CreatedAt = createdAt.Format(time.RFC3339)
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetTrending returns the tags of the most visible posts created in the window, PostsCount is
+// the number of these posts.
+func (s *TagStore) GetTrending(
+	ctx context.Context, window time.Duration, limit int,
+) ([]Tag, error) {
+	query := /* sql */ `
+		SELECT t.id, t.name, t.created_at, COUNT(*) AS posts_count
+		FROM post_tags pt
+		JOIN posts p ON p.id = pt.post_id
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE p.created_at > now() - make_interval(secs => $1)
+			AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+		GROUP BY t.id
+		ORDER BY posts_count DESC, t.name
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, window.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		var createdAt time.Time
+		if err := rows.Scan(&tag.ID, &tag.Name, &createdAt, &tag.PostsCount); err != nil {
+			return nil, err
+		}
+		tag.CreatedAt = createdAt.Format(time.RFC3339)
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *TagStore) Follow(ctx context.Context, userID, tagID int64) error {
+	query := `INSERT INTO tag_followers (user_id, tag_id) VALUES ($1, $2)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userID, tagID)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok {
+			if pgErr.Code == "23505" { // unique_violation
+				return ErrConflict
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *TagStore) Unfollow(ctx context.Context, userID, tagID int64) error {
+	query := `DELETE FROM tag_followers WHERE user_id = $1 AND tag_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userID, tagID)
+	return err
+}
+
+// setPostTags links the post to its tags, creating the new ones. The tags must be normalized.
+func setPostTags(ctx context.Context, tx pgx.Tx, postID int64, tags []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := /* sql */ `
+		INSERT INTO tags (name) SELECT unnest($1::varchar[])
+		ON CONFLICT (name) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, query, tags); err != nil {
+		return err
+	}
+
+	query = /* sql */ `
+		DELETE FROM post_tags
+		WHERE post_id = $1 AND tag_id NOT IN (SELECT id FROM tags WHERE name = ANY($2))
+	`
+	if _, err := tx.Exec(ctx, query, postID, tags); err != nil {
+		return err
+	}
+
+	query = /* sql */ `
+		INSERT INTO post_tags (post_id, tag_id)
+		SELECT $1, id FROM tags WHERE name = ANY($2)
+		ON CONFLICT DO NOTHING
+	`
+	_, err := tx.Exec(ctx, query, postID, tags)
+	return err
+}
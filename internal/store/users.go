@@ -14,14 +14,19 @@ import (
 )
 
 type User struct {
-	ID        int64    `json:"id"`
-	Username  string   `json:"username"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"`
-	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active"`
-	RoleID    int64    `json:"role_id"`
-	Role      Role     `json:"role"`
+	ID             int64    `json:"id"`
+	Username       string   `json:"username"`
+	Email          string   `json:"email"`
+	Password       password `json:"-"`
+	CreatedAt      string   `json:"created_at"`
+	IsActive       bool     `json:"is_active"`
+	DeactivatedAt  string   `json:"deactivated_at,omitempty"`
+	DeactivatedBy  *int64   `json:"deactivated_by,omitempty"`
+	Locale         string   `json:"locale"`
+	RoleID         int64    `json:"role_id"`
+	Role           Role     `json:"role"`
+	FollowersCount int      `json:"followers_count"`
+	FollowingCount int      `json:"following_count"`
 }
 
 type password struct {
@@ -58,8 +63,8 @@ type UserStore struct {
 
 func (s *UserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 	query := /* sql */ `
-		INSERT INTO users (username, email, password, role_id)
-		VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = $4)) RETURNING id, created_at
+		INSERT INTO users (username, email, password, role_id, locale)
+		VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = $4), $5) RETURNING id, created_at
 	`
 
 	role := user.Role.Name
@@ -67,6 +72,10 @@ func (s *UserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 		role = "user"
 	}
 
+	if user.Locale == "" {
+		user.Locale = DefaultLocale
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
@@ -78,6 +87,7 @@ func (s *UserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 		user.Email,
 		user.Password.hash,
 		role,
+		user.Locale,
 	).Scan(
 		&user.ID,
 		&createdAt,
@@ -101,11 +111,11 @@ func (s *UserStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 
 func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	query := /* sql */ `
-		SELECT u.id, u.username, u.email, u.password, u.role_id, u.created_at, r.id, r.name, r.description, r.level
+		SELECT u.id, u.username, u.email, u.password, u.role_id, u.locale, u.created_at, r.id, r.name, r.description, r.level
 		FROM users u
 		JOIN roles r ON (u.role_id = r.id)
 		WHERE u.id = $1
-		AND u.is_active = true
+		AND u.is_active = true AND u.deactivated_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -120,6 +130,7 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.RoleID,
+		&user.Locale,
 		&createdAt,
 		&user.Role.ID,
 		&user.Role.Name,
@@ -140,10 +151,10 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := /* sql */ `
-		SELECT id, username, email, password, created_at
+		SELECT id, username, email, password, locale, created_at
 		FROM users
 		WHERE email = $1
-		AND is_active = true
+		AND is_active = true AND deactivated_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -156,6 +167,7 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Username,
 		&user.Email,
 		&user.Password.hash,
+		&user.Locale,
 		&createdAt,
 	); err != nil {
 		switch err {
@@ -170,8 +182,55 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 	return user, nil
 }
 
+// GetByUsernames returns the active users with the given usernames, the unknown ones are skipped.
+func (s *UserStore) GetByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	query := /* sql */ `
+		SELECT id, username, email, locale, created_at
+		FROM users
+		WHERE username = ANY($1) AND is_active = true AND deactivated_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, usernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		var createdAt time.Time
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.Locale,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		user.CreatedAt = createdAt.Format(time.RFC3339)
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CreateAndInvite creates the user with its invitation and enqueues the invitation email in the
+// same transaction, the email is delivered by the outbox worker once the user exists.
 func (s *UserStore) CreateAndInvite(
-	ctx context.Context, user *User, token string, inviteExpiry time.Duration,
+	ctx context.Context,
+	user *User,
+	token string,
+	inviteExpiry time.Duration,
+	invitation *OutboxEmail,
 ) error {
 	return withTx(s.db, ctx, func(tx pgx.Tx) error {
 		// create the user
@@ -184,10 +243,67 @@ func (s *UserStore) CreateAndInvite(
 			return err
 		}
 
+		// enqueue the invitation email
+		if err := enqueueEmail(ctx, tx, invitation); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
 
+// RotateInvitation replaces the pending invitations of an inactive user with a new one and enqueues
+// the invitation email built for the user in the same transaction. Active, deactivated and unknown
+// users are not found.
+func (s *UserStore) RotateInvitation(
+	ctx context.Context,
+	email, token string,
+	inviteExpiry time.Duration,
+	invitation func(*User) (*OutboxEmail, error),
+) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		user := &User{}
+		query := /* sql */ `SELECT id, username, email, locale, created_at FROM users
+								WHERE email = $1 AND is_active = false AND deactivated_at IS NULL
+								FOR UPDATE`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var createdAt time.Time
+		if err := tx.QueryRow(queryCtx, query, email).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.Locale,
+			&createdAt,
+		); err != nil {
+			switch err {
+			case pgx.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		user.CreatedAt = createdAt.Format(time.RFC3339)
+
+		if err := s.deleteUserInvitations(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		if err := s.createUserInvitation(ctx, tx, token, inviteExpiry, user.ID); err != nil {
+			return err
+		}
+
+		email, err := invitation(user)
+		if err != nil {
+			return err
+		}
+
+		return enqueueEmail(ctx, tx, email)
+	})
+}
+
 func (s *UserStore) Activate(ctx context.Context, token string) error {
 	return withTx(s.db, ctx, func(tx pgx.Tx) error {
 
@@ -212,9 +328,226 @@ func (s *UserStore) Activate(ctx context.Context, token string) error {
 	})
 }
 
+// CreatePasswordReset stores a hashed password reset token for the user, replacing the tokens
+// previously requested so that only the latest email can be used, and enqueues the email.
+func (s *UserStore) CreatePasswordReset(
+	ctx context.Context, userID int64, token string, expiry time.Duration, email *OutboxEmail,
+) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := s.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := /* sql */ `INSERT INTO password_resets
+								(token, user_id, expiry)
+								VALUES ($1, $2, $3)`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(queryCtx, query, token, userID, time.Now().Add(expiry)); err != nil {
+			return err
+		}
+
+		return enqueueEmail(ctx, tx, email)
+	})
+}
+
+// ResetPassword sets a new password for the user of a password reset token. The token can only be
+// used once and every session of the user is revoked.
+func (s *UserStore) ResetPassword(ctx context.Context, token, newPassword string) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		userID, err := s.getUserIDFromResetToken(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		var pass password
+		if err := pass.Set(newPassword); err != nil {
+			return err
+		}
+
+		query := /* sql */ `UPDATE users SET password = $1 WHERE id = $2`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.Exec(queryCtx, query, pass.hash, userID); err != nil {
+			return err
+		}
+
+		if err := s.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return revokeUserSessions(ctx, tx, userID)
+	})
+}
+
+// UpdateLocale sets the locale the user prefers for emails and messages
+func (s *UserStore) UpdateLocale(ctx context.Context, userID int64, locale string) error {
+	query := /* sql */ `UPDATE users SET locale = $1
+		WHERE id = $2 AND is_active = true AND deactivated_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctx, query, locale, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetAccount returns a user by ID whether it is active or not, for the admins managing accounts.
+func (s *UserStore) GetAccount(ctx context.Context, id int64) (*User, error) {
+	query := /* sql */ `
+		SELECT u.id, u.username, u.email, u.is_active, u.deactivated_at, u.deactivated_by, u.role_id, u.locale, u.created_at, r.id, r.name, r.description, r.level
+		FROM users u
+		JOIN roles r ON (u.role_id = r.id)
+		WHERE u.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user, err := scanAccount(s.db.QueryRow(ctx, query, id))
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// Search lists the users matching uq, newest first. The search term matches usernames and emails.
+func (s *UserStore) Search(ctx context.Context, uq PaginatedUserQuery) ([]User, error) {
+	query := /* sql */ `
+		SELECT u.id, u.username, u.email, u.is_active, u.deactivated_at, u.deactivated_by, u.role_id, u.locale, u.created_at, r.id, r.name, r.description, r.level
+		FROM users u
+		JOIN roles r ON (u.role_id = r.id)
+		WHERE ($2 = '' OR u.username ILIKE '%' || $2 || '%' OR u.email ILIKE '%' || $2 || '%')
+			AND ($3 = '' OR r.name = $3)
+			AND ($4::boolean IS NULL OR (u.is_active AND u.deactivated_at IS NULL) = $4)
+			AND ($5::timestamptz IS NULL OR (u.created_at, u.id) < ($5, $6::bigint))
+		ORDER BY u.created_at DESC, u.id DESC
+		LIMIT $1
+	`
+
+	var cursorCreatedAt *time.Time
+	var cursorID int64
+	if uq.Cursor != nil {
+		cursorCreatedAt = &uq.Cursor.CreatedAt
+		cursorID = uq.Cursor.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(
+		ctx, query, uq.Limit, uq.Search, uq.Role, uq.Active, cursorCreatedAt, cursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SetRole gives the role to the user
+func (s *UserStore) SetRole(ctx context.Context, userID, roleID int64) error {
+	query := /* sql */ `UPDATE users SET role_id = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctx, query, roleID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Deactivate bans the user on behalf of the admin. Deactivated users are signed out of all their
+// sessions and can no longer sign in, use their access tokens or get a new invitation.
+func (s *UserStore) Deactivate(ctx context.Context, userID, adminID int64) error {
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		query := /* sql */ `UPDATE users SET deactivated_at = now(), deactivated_by = $2
+			WHERE id = $1`
+
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		tag, err := tx.Exec(queryCtx, query, userID, adminID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return revokeUserSessions(ctx, tx, userID)
+	})
+}
+
+// Reactivate lifts the deactivation of the user. A user who never confirmed its email still has
+// to do so.
+func (s *UserStore) Reactivate(ctx context.Context, userID int64) error {
+	query := /* sql */ `UPDATE users SET deactivated_at = NULL, deactivated_by = NULL WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the user for good along with its posts and comments. Followers, reactions and
+// sessions are removed by the database.
 func (s *UserStore) Delete(ctx context.Context, userID int64) error {
 
 	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := s.deleteContent(ctx, tx, userID); err != nil {
+			return err
+		}
+
 		if err := s.delete(ctx, tx, userID); err != nil {
 			return err
 		}
@@ -255,11 +588,35 @@ func (s *UserStore) delete(ctx context.Context, tx pgx.Tx, userID int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := tx.Exec(ctx, query, userID)
+	tag, err := tx.Exec(ctx, query, userID)
 	if err != nil {
 		return err
 	}
 
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// deleteContent removes the comments of the user, the comments on its posts and its posts.
+func (s *UserStore) deleteContent(ctx context.Context, tx pgx.Tx, userID int64) error {
+	queries := []string{
+		/* sql */ `DELETE FROM comments WHERE user_id = $1
+			OR post_id IN (SELECT id FROM posts WHERE user_id = $1)`,
+		/* sql */ `DELETE FROM posts WHERE user_id = $1`,
+	}
+
+	for _, query := range queries {
+		queryCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		_, err := tx.Exec(queryCtx, query, userID)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -303,7 +660,7 @@ func (s *UserStore) getUserFromToken(
 	query := /* sql */ `SELECT u.id, u.username, u.email, u.created_at, u.is_active FROM users u
 							JOIN user_invitations i
 							ON u.id = i.user_id
-							WHERE i.token = $1 and i.expiry > $2`
+							WHERE i.token = $1 and i.expiry > $2 AND u.deactivated_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -331,3 +688,73 @@ func (s *UserStore) getUserFromToken(
 	user.CreatedAt = createdAt.Format(time.RFC3339)
 	return user, nil
 }
+
+func (s *UserStore) getUserIDFromResetToken(
+	ctx context.Context, tx pgx.Tx, token string,
+) (int64, error) {
+
+	query := /* sql */ `SELECT r.user_id FROM password_resets r
+							JOIN users u
+							ON u.id = r.user_id
+							WHERE r.token = $1 AND r.expiry > $2 AND u.is_active = true
+								AND u.deactivated_at IS NULL
+							FOR UPDATE OF r`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+
+	var userID int64
+	if err := tx.QueryRow(ctx, query, hashToken, time.Now()).Scan(&userID); err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (s *UserStore) deletePasswordResets(ctx context.Context, tx pgx.Tx, userID int64) error {
+	query := /* sql */ `DELETE FROM password_resets WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.Exec(ctx, query, userID)
+	return err
+}
+
+// scanAccount reads a user with its status and role, as selected by GetAccount and Search
+func scanAccount(row pgx.Row) (*User, error) {
+	user := &User{}
+	var createdAt time.Time
+	var deactivatedAt *time.Time
+	if err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.IsActive,
+		&deactivatedAt,
+		&user.DeactivatedBy,
+		&user.RoleID,
+		&user.Locale,
+		&createdAt,
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Description,
+		&user.Role.Level,
+	); err != nil {
+		return nil, err
+	}
+
+	if deactivatedAt != nil {
+		user.DeactivatedAt = deactivatedAt.Format(time.RFC3339)
+	}
+	user.CreatedAt = createdAt.Format(time.RFC3339)
+	return user, nil
+}
@@ -0,0 +1,33 @@
+import { useState } from "react";
+import { useNavigate, useParams } from "react-router-dom";
+import { API_URL } from "./App";
+
+export const ResetPasswordPage = () => {
+  const { token = "" } = useParams();
+  const [password, setPassword] = useState("");
+  const redirect = useNavigate();
+  const handleReset = async () => {
+    const response = await fetch(`${API_URL}/authentication/password/reset`, {
+      method: "POST",
+      body: JSON.stringify({ token, password }),
+    });
+
+    if (response.ok) {
+      redirect("/");
+    } else {
+      alert("Failed to reset password");
+    }
+  };
+  return (
+    <div>
+      <h1>Reset Password</h1>
+      <input
+        type="password"
+        placeholder="New password"
+        value={password}
+        onChange={(e) => setPassword(e.target.value)}
+      />
+      <button onClick={handleReset}>Click to reset</button>
+    </div>
+  );
+};
@@ -4,6 +4,7 @@ import "./index.css";
 import App from "./App.tsx";
 import { createBrowserRouter, RouterProvider } from "react-router-dom";
 import { ConfirmationPage } from "./ConfirmationPage.tsx";
+import { ResetPasswordPage } from "./ResetPasswordPage.tsx";
 
 const router = createBrowserRouter([
   {
@@ -14,6 +15,10 @@ const router = createBrowserRouter([
     path: "/confirm/:token",
     element: <ConfirmationPage />,
   },
+  {
+    path: "/reset-password/:token",
+    element: <ResetPasswordPage />,
+  },
 ]);
 
 createRoot(document.getElementById("root")!).render(